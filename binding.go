@@ -0,0 +1,221 @@
+package restik
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// binding sources of argument fields
+const (
+	pathSource   = "path"
+	querySource  = "query"
+	headerSource = "header"
+	formSource   = "form"
+)
+
+var bindingSources = []string{pathSource, querySource, headerSource, formSource}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// binding describe how to fill argument struct fields from path variables,
+// query parameters and headers. It is built once on route creation
+type binding struct {
+	fields []fieldBinding
+	form   bool
+}
+
+type fieldBinding struct {
+	index  []int
+	source string
+	name   string
+}
+
+// newBinding return binding for struct type t or nil if t has not fields
+// with binding tags
+//
+// Supported tags:
+//
+//	`path:"id"`
+//	`query:"limit"`
+//	`header:"X-Tenant"`
+//	`form:"avatar"`
+func newBinding(t reflect.Type) *binding {
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	b := &binding{}
+	b.collect(t, nil)
+	if len(b.fields) == 0 {
+		return nil
+	}
+	return b
+}
+
+func (b *binding) collect(t reflect.Type, index []int) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldIndex := append(append([]int{}, index...), i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			b.collect(field.Type, fieldIndex)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		for _, source := range bindingSources {
+			name := tagName(field.Tag.Get(source))
+			if name == "" {
+				continue
+			}
+			b.fields = append(b.fields, fieldBinding{fieldIndex, source, name})
+			b.form = b.form || source == formSource
+		}
+	}
+}
+
+// bind fill fields of struct value v from request
+func (b *binding) bind(rr *Request, v reflect.Value) error {
+	var query map[string][]string
+	for _, fb := range b.fields {
+		var values []string
+		switch fb.source {
+		case pathSource:
+			if s, ok := rr.Vars.StringOk(fb.name); ok {
+				values = []string{s}
+			}
+		case querySource:
+			if query == nil {
+				query = rr.URL.Query()
+			}
+			values = query[fb.name]
+		case headerSource:
+			values = rr.Headers.Values(fb.name)
+		case formSource:
+			field := v.FieldByIndex(fb.index)
+			if isFileType(field.Type()) {
+				if rr.MultipartForm != nil && len(rr.MultipartForm.File[fb.name]) > 0 {
+					setFileField(field, rr.MultipartForm.File[fb.name])
+				}
+				continue
+			}
+			values = rr.PostForm[fb.name]
+		}
+		if len(values) == 0 {
+			continue
+		}
+		field := v.FieldByIndex(fb.index)
+		if err := setFieldValues(field, values); err != nil {
+			return NewBadRequestError("invalid_param",
+				fmt.Sprintf("Invalid %s parameter %q: %s", fb.source, fb.name, err))
+		}
+	}
+	return nil
+}
+
+// tagName return name part of tag value like "name,omitempty"
+func tagName(tag string) string {
+	if i := strings.Index(tag, ","); i >= 0 {
+		tag = tag[:i]
+	}
+	if tag == "-" {
+		return ""
+	}
+	return tag
+}
+
+// setFieldValues set field from string values with type conversion.
+// Slice fields take all values, comma separated single value is split
+func setFieldValues(field reflect.Value, values []string) error {
+	if field.Kind() == reflect.Slice && field.Type().Elem().Kind() != reflect.Uint8 &&
+		!reflect.PtrTo(field.Type()).Implements(textUnmarshalerType) {
+		if len(values) == 1 {
+			values = strings.Split(values[0], ",")
+		}
+		slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for i, s := range values {
+			if err := setFieldValue(slice.Index(i), s); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+	return setFieldValue(field, values[0])
+}
+
+func setFieldValue(field reflect.Value, s string) error {
+	if field.Kind() == reflect.Ptr {
+		ptr := reflect.New(field.Type().Elem())
+		if err := setFieldValue(ptr.Elem(), s); err != nil {
+			return err
+		}
+		field.Set(ptr)
+		return nil
+	}
+
+	switch field.Type() {
+	case timeType:
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return fmt.Errorf("expected time in RFC3339 format")
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	case durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("expected duration")
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	if field.CanAddr() && field.Addr().Type().Implements(textUnmarshalerType) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(s)
+	case reflect.Bool:
+		// bare flag like "?verbose" mean true
+		if s == "" {
+			field.SetBool(true)
+			break
+		}
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("expected bool")
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("expected %s", field.Kind())
+		}
+		field.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("expected %s", field.Kind())
+		}
+		field.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("expected %s", field.Kind())
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package restik
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bindingPage struct {
+	Limit int `query:"limit"`
+}
+
+type bindingArg struct {
+	bindingPage
+	ID      int64         `path:"id"`
+	Verbose bool          `query:"verbose"`
+	Ratio   float64       `query:"ratio"`
+	Since   time.Time     `query:"since"`
+	Timeout time.Duration `query:"timeout"`
+	Tags    []string      `query:"tag"`
+	IDs     []int         `query:"ids"`
+	Offset  *uint         `query:"offset"`
+	Tenant  string        `header:"X-Tenant"`
+}
+
+func TestBinding(t *testing.T) {
+	var got bindingArg
+	r := NewRouter()
+	r.Get("/items/{id}", func(a bindingArg) (string, error) {
+		got = a
+		return "ok", nil
+	})
+
+	offset := uint(5)
+	since := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		status int
+		want   bindingArg
+		msg    string
+	}{
+		{"empty", "/items/1", "", http.StatusOK, bindingArg{ID: 1}, ""},
+		{"int and embedded", "/items/1?limit=10", "", http.StatusOK, bindingArg{bindingPage: bindingPage{10}, ID: 1}, ""},
+		{"bool", "/items/1?verbose=false", "", http.StatusOK, bindingArg{ID: 1}, ""},
+		{"bare bool flag", "/items/1?verbose", "", http.StatusOK, bindingArg{ID: 1, Verbose: true}, ""},
+		{"float", "/items/1?ratio=0.5", "", http.StatusOK, bindingArg{ID: 1, Ratio: 0.5}, ""},
+		{"time", "/items/1?since=2024-01-02T03:04:05Z", "", http.StatusOK, bindingArg{ID: 1, Since: since}, ""},
+		{"duration", "/items/1?timeout=1m30s", "", http.StatusOK, bindingArg{ID: 1, Timeout: 90 * time.Second}, ""},
+		{"repeated slice", "/items/1?tag=a&tag=b", "", http.StatusOK, bindingArg{ID: 1, Tags: []string{"a", "b"}}, ""},
+		{"comma slice", "/items/1?ids=1,2,3", "", http.StatusOK, bindingArg{ID: 1, IDs: []int{1, 2, 3}}, ""},
+		{"pointer", "/items/1?offset=5", "", http.StatusOK, bindingArg{ID: 1, Offset: &offset}, ""},
+		{"header", "/items/1", "acme", http.StatusOK, bindingArg{ID: 1, Tenant: "acme"}, ""},
+		{"invalid path", "/items/x", "", http.StatusBadRequest, bindingArg{}, `path parameter \"id\": expected int64`},
+		{"invalid int", "/items/1?limit=ten", "", http.StatusBadRequest, bindingArg{}, `query parameter \"limit\": expected int`},
+		{"invalid bool", "/items/1?verbose=maybe", "", http.StatusBadRequest, bindingArg{}, `query parameter \"verbose\": expected bool`},
+		{"invalid float", "/items/1?ratio=half", "", http.StatusBadRequest, bindingArg{}, `query parameter \"ratio\": expected float64`},
+		{"invalid time", "/items/1?since=yesterday", "", http.StatusBadRequest, bindingArg{}, `query parameter \"since\": expected time`},
+		{"invalid slice item", "/items/1?ids=1,x", "", http.StatusBadRequest, bindingArg{}, `query parameter \"ids\": expected int`},
+		{"negative uint", "/items/1?offset=-1", "", http.StatusBadRequest, bindingArg{}, `query parameter \"offset\": expected uint`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = bindingArg{}
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("X-Tenant", tt.header)
+			}
+			w := httptest.NewRecorder()
+			r.Handler().ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body)
+			}
+			if tt.msg != "" {
+				if !strings.Contains(w.Body.String(), tt.msg) {
+					t.Errorf("body = %s, want %s", w.Body, tt.msg)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("arg = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package restik
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Codec encode replies and decode arguments in some media type
+type Codec interface {
+	// ContentType return value of Content-Type header,
+	// e.g. "application/json; charset=UTF-8"
+	ContentType() string
+	NewEncoder(w io.Writer) Encoder
+	NewDecoder(r io.Reader) Decoder
+}
+
+// Encoder write values to stream
+type Encoder interface {
+	Encode(v interface{}) error
+}
+
+// Decoder read values from stream
+type Decoder interface {
+	Decode(v interface{}) error
+}
+
+var (
+	// JSONCodec is codec of application/json media type
+	JSONCodec Codec = jsonCodec{}
+	// XMLCodec is codec of application/xml media type. It is not
+	// registered by default, because maps can not be encoded to XML
+	XMLCodec Codec = xmlCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string {
+	return "application/json; charset=UTF-8"
+}
+
+func (jsonCodec) NewEncoder(w io.Writer) Encoder {
+	return json.NewEncoder(w)
+}
+
+func (jsonCodec) NewDecoder(r io.Reader) Decoder {
+	return json.NewDecoder(r)
+}
+
+type xmlCodec struct{}
+
+func (xmlCodec) ContentType() string {
+	return "application/xml; charset=UTF-8"
+}
+
+func (xmlCodec) NewEncoder(w io.Writer) Encoder {
+	return xml.NewEncoder(w)
+}
+
+func (xmlCodec) NewDecoder(r io.Reader) Decoder {
+	return xml.NewDecoder(r)
+}
+
+// codecs is list of codecs, first codec is default
+type codecs []Codec
+
+// mediaType return media type of codec without parameters
+func mediaType(c Codec) string {
+	mt, _, err := mime.ParseMediaType(c.ContentType())
+	if err != nil {
+		return c.ContentType()
+	}
+	return mt
+}
+
+// add add codec or replace codec with the same media type
+func (cs codecs) add(c Codec) codecs {
+	mt := mediaType(c)
+	for i := range cs {
+		if mediaType(cs[i]) == mt {
+			cs[i] = c
+			return cs
+		}
+	}
+	return append(cs, c)
+}
+
+// find return codec by media type. Structured syntax suffix like
+// application/problem+json is matched to application/json codec
+func (cs codecs) find(mt string) (Codec, bool) {
+	for _, c := range cs {
+		if mediaType(c) == mt {
+			return c, true
+		}
+	}
+	if i := strings.LastIndex(mt, "+"); i >= 0 {
+		if slash := strings.Index(mt, "/"); slash >= 0 && slash < i {
+			return cs.find(mt[:slash+1] + mt[i+1:])
+		}
+	}
+	return nil, false
+}
+
+// forContentType return codec for request Content-Type header.
+// Default codec is returned for empty header
+func (cs codecs) forContentType(contentType string) (Codec, bool) {
+	if contentType == "" {
+		return cs[0], true
+	}
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	return cs.find(mt)
+}
+
+// forAccept return codec for request Accept header by media types
+// preference. Default codec is returned for empty header
+func (cs codecs) forAccept(accept string) (Codec, bool) {
+	if accept == "" {
+		return cs[0], true
+	}
+	for _, mt := range parseAccept(accept) {
+		switch {
+		case mt == "*/*":
+			return cs[0], true
+		case strings.HasSuffix(mt, "/*"):
+			prefix := strings.TrimSuffix(mt, "*")
+			for _, c := range cs {
+				if strings.HasPrefix(mediaType(c), prefix) {
+					return c, true
+				}
+			}
+		default:
+			if c, ok := cs.find(mt); ok {
+				return c, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// parseAccept return acceptable media types of Accept header
+// sorted by quality. Media types with zero quality are skipped
+func parseAccept(accept string) []string {
+	type acceptItem struct {
+		mediaType string
+		q         float64
+	}
+	var items []acceptItem
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if s, ok := params["q"]; ok {
+			if v, err := strconv.ParseFloat(s, 64); err == nil {
+				q = v
+			}
+		}
+		if q > 0 {
+			items = append(items, acceptItem{mt, q})
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].q > items[j].q
+	})
+	mediaTypes := make([]string, len(items))
+	for i, item := range items {
+		mediaTypes[i] = item.mediaType
+	}
+	return mediaTypes
+}
+
+// RegisterCodec add codecs to router. Codec with the same media type
+// is replaced
+func (r *Router) RegisterCodec(cs ...Codec) *Router {
+	for _, c := range cs {
+		r.codecs = r.codecs.add(c)
+	}
+	return r
+}
+
+// newResponseWriter create ResponseWriter with codec negotiated
+// by Accept header of request. Errors are rendered as problem details
+// if client accept them
+func (r *Router) newResponseWriter(hw http.ResponseWriter, hr *http.Request) ResponseWriter {
+	rw := NewResponseWriter(hw, r.replyImpl)
+	rw.errorMapper = r.errorMapper
+	rw.defaultCodec = r.codecs[0]
+	accept := hr.Header.Get("Accept")
+	if _, ok := r.replyImpl.(*ProblemReply); !ok && acceptsProblem(accept) {
+		rw.commonReply = &ProblemReply{Reply: r.replyImpl}
+	}
+	codec, ok := r.codecs.forAccept(accept)
+	if ok {
+		rw.codec = codec
+	} else {
+		rw.notAcceptable = true
+	}
+	return rw
+}
@@ -0,0 +1,50 @@
+package restik
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const browserAccept = "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+
+func mapHandler() (map[string]int, error) {
+	return map[string]int{"a": 1}, nil
+}
+
+func TestBrowserAcceptUsesJSON(t *testing.T) {
+	r := NewRouter()
+	r.Get("/map", mapHandler)
+
+	req := httptest.NewRequest("GET", "/map", nil)
+	req.Header.Set("Accept", browserAccept)
+	w := httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+}
+
+func TestEncodeFailureFallsBackToDefaultCodec(t *testing.T) {
+	r := NewRouter()
+	r.RegisterCodec(XMLCodec)
+	r.Get("/map", mapHandler)
+
+	req := httptest.NewRequest("GET", "/map", nil)
+	req.Header.Set("Accept", "application/xml")
+	w := httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, req)
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(w.Body.String(), `"code":"encode_failed"`) {
+		t.Errorf("body = %s", w.Body)
+	}
+}
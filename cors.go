@@ -0,0 +1,253 @@
+package restik
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var defaultCorsMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost,
+	http.MethodPut, http.MethodPatch, http.MethodDelete,
+}
+
+// CorsMiddleware implement cross-origin resource sharing. Preflight
+// requests of disallowed origins, methods or headers are answered with
+// 403 status, other requests of disallowed origins are passed without
+// CORS headers, so browsers block their responses.
+// Configuration is validated when middleware is added by Use, which
+// panics if AllowedOriginPatterns contain invalid expression or if any
+// origin is allowed by "*" with AllowCredentials
+//
+// Using:
+//
+//	r.Use(&restik.CorsMiddleware{
+//		AllowedOrigins:   []string{"https://example.com", "https://*.example.com"},
+//		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+//		ExposedHeaders:   []string{"X-Total-Count"},
+//		AllowCredentials: true,
+//		MaxAge:           time.Hour,
+//	})
+type CorsMiddleware struct {
+	// AllowedOrigins is list of allowed origins. Origin can contain
+	// wildcards like "https://*.example.com", "*" allow any origin
+	AllowedOrigins []string
+	// AllowedOriginPatterns is list of regular expressions of allowed origins
+	AllowedOriginPatterns []string
+	// AllowOriginFunc allow origins which are not allowed by lists
+	AllowOriginFunc func(origin string, r *Request) bool
+	// AllowedMethods is list of methods allowed for preflight requests.
+	// Default is GET, HEAD, POST, PUT, PATCH and DELETE
+	AllowedMethods []string
+	// AllowedHeaders is list of request headers allowed for preflight
+	// requests. Empty list or "*" allow any requested headers
+	AllowedHeaders []string
+	// ExposedHeaders is list of response headers available to client
+	ExposedHeaders []string
+	// AllowCredentials allow requests with cookies and authorization.
+	// It can not be used with "*" origin
+	AllowCredentials bool
+	// MaxAge is time of caching preflight results by client
+	MaxAge time.Duration
+
+	// AllowedOrigin is single allowed origin.
+	// Deprecated: use AllowedOrigins
+	AllowedOrigin string
+}
+
+// corsPolicy is compiled configuration of CorsMiddleware
+type corsPolicy struct {
+	allowAll      bool
+	origins       map[string]bool
+	wildcards     []*regexp.Regexp
+	patterns      []*regexp.Regexp
+	methods       map[string]bool
+	anyHeader     bool
+	headers       map[string]bool
+	methodsHeader string
+	exposeHeader  string
+	maxAgeHeader  string
+}
+
+func (mw *CorsMiddleware) Middleware(next HandlerFunc) HandlerFunc {
+	p, err := mw.compile()
+	if err != nil {
+		panic(err)
+	}
+	return func(w ResponseWriter, r *Request) {
+		header := w.Header()
+		addVary(header, "Origin")
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next(w, r)
+			return
+		}
+		allowed := p.allowAll || p.allowOrigin(origin) ||
+			(mw.AllowOriginFunc != nil && mw.AllowOriginFunc(origin, r))
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			addVary(header, "Access-Control-Request-Method")
+			addVary(header, "Access-Control-Request-Headers")
+			mw.preflight(w, r, p, origin, allowed)
+			return
+		}
+		if allowed {
+			mw.setOrigin(header, p, origin)
+			if p.exposeHeader != "" {
+				header.Set("Access-Control-Expose-Headers", p.exposeHeader)
+			}
+		}
+		next(w, r)
+	}
+}
+
+// preflight answer preflight request
+func (mw *CorsMiddleware) preflight(w ResponseWriter, r *Request, p *corsPolicy, origin string, allowed bool) {
+	if !allowed {
+		w.WriteError(NewForbiddenError("origin_not_allowed", "Origin not allowed"))
+		return
+	}
+	method := r.Header.Get("Access-Control-Request-Method")
+	if !p.methods[method] {
+		w.WriteError(NewForbiddenError("cors_method_not_allowed", "Method "+method+" not allowed"))
+		return
+	}
+	var requested []string
+	for _, v := range r.Header.Values("Access-Control-Request-Headers") {
+		for _, h := range strings.Split(v, ",") {
+			if h = strings.TrimSpace(h); h == "" {
+				continue
+			}
+			if !p.anyHeader && !p.headers[strings.ToLower(h)] {
+				w.WriteError(NewForbiddenError("cors_header_not_allowed", "Header "+h+" not allowed"))
+				return
+			}
+			requested = append(requested, h)
+		}
+	}
+
+	header := w.Header()
+	mw.setOrigin(header, p, origin)
+	header.Set("Access-Control-Allow-Methods", p.methodsHeader)
+	if len(requested) > 0 {
+		header.Set("Access-Control-Allow-Headers", strings.Join(requested, ", "))
+	}
+	if p.maxAgeHeader != "" {
+		header.Set("Access-Control-Max-Age", p.maxAgeHeader)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setOrigin set allowed origin and credentials headers
+func (mw *CorsMiddleware) setOrigin(header http.Header, p *corsPolicy, origin string) {
+	if p.allowAll {
+		header.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	header.Set("Access-Control-Allow-Origin", origin)
+	if mw.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// Validate return error of invalid configuration
+func (mw *CorsMiddleware) Validate() error {
+	_, err := mw.compile()
+	return err
+}
+
+func (mw *CorsMiddleware) compile() (*corsPolicy, error) {
+	p := &corsPolicy{
+		origins: map[string]bool{},
+		methods: map[string]bool{},
+		headers: map[string]bool{},
+	}
+	origins := mw.AllowedOrigins
+	if mw.AllowedOrigin != "" {
+		origins = append(origins[:len(origins):len(origins)], mw.AllowedOrigin)
+	}
+	for _, o := range origins {
+		switch {
+		case o == "*":
+			p.allowAll = true
+		case strings.Contains(o, "*"):
+			parts := strings.Split(strings.ToLower(o), "*")
+			for i := range parts {
+				parts[i] = regexp.QuoteMeta(parts[i])
+			}
+			p.wildcards = append(p.wildcards, regexp.MustCompile("^"+strings.Join(parts, ".*")+"$"))
+		default:
+			p.origins[strings.ToLower(o)] = true
+		}
+	}
+	if p.allowAll && mw.AllowCredentials {
+		// any site could make credentialed requests
+		return nil, errors.New("restik: CorsMiddleware can not allow credentials for any origin")
+	}
+	for _, pattern := range mw.AllowedOriginPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("restik: CorsMiddleware origin pattern: %w", err)
+		}
+		p.patterns = append(p.patterns, re)
+	}
+
+	methods := mw.AllowedMethods
+	if len(methods) == 0 {
+		methods = defaultCorsMethods
+	}
+	for _, m := range methods {
+		p.methods[strings.ToUpper(m)] = true
+	}
+	p.methodsHeader = strings.Join(methods, ", ")
+
+	p.anyHeader = len(mw.AllowedHeaders) == 0
+	for _, h := range mw.AllowedHeaders {
+		if h == "*" {
+			p.anyHeader = true
+		}
+		p.headers[strings.ToLower(h)] = true
+	}
+	p.exposeHeader = strings.Join(mw.ExposedHeaders, ", ")
+	if mw.MaxAge > 0 {
+		p.maxAgeHeader = strconv.Itoa(int(mw.MaxAge / time.Second))
+	}
+	return p, nil
+}
+
+// allowOrigin report whether origin is allowed by lists. Origins and
+// wildcards are matched case-insensitively, user patterns are matched
+// as is
+func (p *corsPolicy) allowOrigin(origin string) bool {
+	lower := strings.ToLower(origin)
+	if p.origins[lower] {
+		return true
+	}
+	for _, re := range p.wildcards {
+		if re.MatchString(lower) {
+			return true
+		}
+	}
+	for _, re := range p.patterns {
+		if re.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// addVary add name to Vary header if it is not listed
+func addVary(header http.Header, name string) {
+	for _, v := range header.Values("Vary") {
+		for _, s := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(s), name) {
+				return
+			}
+		}
+	}
+	header.Add("Vary", name)
+}
@@ -0,0 +1,75 @@
+package restik
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCorsInvalidConfigPanicsOnUse(t *testing.T) {
+	tests := []struct {
+		name string
+		mw   *CorsMiddleware
+		use  func(r *Router, mw Middleware)
+	}{
+		{"credentials with any origin router", &CorsMiddleware{AllowedOrigins: []string{"*"}, AllowCredentials: true},
+			func(r *Router, mw Middleware) { r.Use(mw) }},
+		{"invalid pattern router", &CorsMiddleware{AllowedOriginPatterns: []string{"("}},
+			func(r *Router, mw Middleware) { r.Use(mw) }},
+		{"invalid pattern group", &CorsMiddleware{AllowedOriginPatterns: []string{"("}},
+			func(r *Router, mw Middleware) { r.Group("/api", mw) }},
+		{"credentials with any origin group use", &CorsMiddleware{AllowedOrigins: []string{"*"}, AllowCredentials: true},
+			func(r *Router, mw Middleware) { r.Group("/api").Use(mw) }},
+		{"invalid pattern route", &CorsMiddleware{AllowedOriginPatterns: []string{"("}},
+			func(r *Router, mw Middleware) { r.Get("/hello", helloHandler).Use(mw) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.mw.Validate(); err == nil {
+				t.Error("Validate returned nil")
+			}
+			defer func() {
+				if recover() == nil {
+					t.Error("Use did not panic")
+				}
+			}()
+			tt.use(NewRouter(), tt.mw)
+		})
+	}
+}
+
+func TestCorsAllowOrigin(t *testing.T) {
+	r := NewRouter()
+	r.Use(&CorsMiddleware{
+		AllowedOrigins:        []string{"https://Example.com", "https://*.example.org"},
+		AllowedOriginPatterns: []string{`^https://App-[0-9]+\.example\.net$`},
+		AllowCredentials:      true,
+	})
+	r.Get("/hello", helloHandler)
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"https://example.com", true},
+		{"https://EXAMPLE.com", true},
+		{"https://api.Example.org", true},
+		{"https://App-1.example.net", true},
+		{"https://app-1.example.net", false},
+		{"https://evil.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/hello", nil)
+			req.Header.Set("Origin", tt.origin)
+			w := httptest.NewRecorder()
+			r.Handler().ServeHTTP(w, req)
+			got := w.Header().Get("Access-Control-Allow-Origin")
+			if tt.allowed && got != tt.origin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.origin)
+			}
+			if !tt.allowed && (got != "" || w.Header().Get("Access-Control-Allow-Credentials") != "") {
+				t.Errorf("disallowed origin got CORS headers %v", w.Header())
+			}
+		})
+	}
+}
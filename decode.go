@@ -0,0 +1,199 @@
+package restik
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// DecodeOptions configure decoding of request bodies into handler
+// arguments
+type DecodeOptions struct {
+	// MaxBodySize limit size of request body, larger requests are
+	// answered with 413 status. Zero is no limit
+	MaxBodySize int64
+	// DisallowUnknownFields reject bodies with fields missing in argument
+	DisallowUnknownFields bool
+	// UseNumber decode numbers into interface{} as json.Number
+	UseNumber bool
+	// RequireContentType reject bodies without Content-Type header or
+	// with media type without registered codec with 415 status instead
+	// of decoding them by default codec
+	RequireContentType bool
+}
+
+// SetDecodeOptions set options of decoding request bodies for all routes
+//
+// Using:
+//
+//	r.SetDecodeOptions(restik.DecodeOptions{
+//		MaxBodySize:           1 << 20,
+//		DisallowUnknownFields: true,
+//	})
+func (r *Router) SetDecodeOptions(opts DecodeOptions) {
+	r.decodeOptions = opts
+}
+
+// DecodeOptions set options of decoding request body of route.
+// They replace options of router
+func (rt *Route) DecodeOptions(opts DecodeOptions) *Route {
+	rt.decodeOptions = &opts
+	return rt
+}
+
+// getDecodeOptions return options of route or router
+func (rt *Route) getDecodeOptions() DecodeOptions {
+	if rt.decodeOptions != nil {
+		return *rt.decodeOptions
+	}
+	if rt.router != nil {
+		return rt.router.decodeOptions
+	}
+	return DecodeOptions{}
+}
+
+// readBody read request body with size limit of options
+func readBody(rr *Request, opts DecodeOptions) ([]byte, error) {
+	if opts.MaxBodySize <= 0 {
+		raw, err := ioutil.ReadAll(rr.Body)
+		if err != nil {
+			return nil, newStatusError(http.StatusBadRequest, "read_failed", "Failed to read request body: "+err.Error()).WithCause(err)
+		}
+		return raw, nil
+	}
+	if rr.ContentLength > opts.MaxBodySize {
+		return nil, NewRequestEntityTooLargeError()
+	}
+	body := &limitedReader{ReadCloser: rr.Body, n: opts.MaxBodySize}
+	raw, err := ioutil.ReadAll(body)
+	if body.exceeded {
+		return nil, NewRequestEntityTooLargeError()
+	}
+	if err != nil {
+		return nil, newStatusError(http.StatusBadRequest, "read_failed", "Failed to read request body: "+err.Error()).WithCause(err)
+	}
+	return raw, nil
+}
+
+// decode decode raw body into v by codec with options
+func decode(codec Codec, raw []byte, v interface{}, opts DecodeOptions) error {
+	dec := codec.NewDecoder(bytes.NewReader(raw))
+	if opts.DisallowUnknownFields {
+		if d, ok := dec.(interface{ DisallowUnknownFields() }); ok {
+			d.DisallowUnknownFields()
+		}
+	}
+	if opts.UseNumber {
+		if d, ok := dec.(interface{ UseNumber() }); ok {
+			d.UseNumber()
+		}
+	}
+	if err := dec.Decode(v); err != nil {
+		return decodeError(err)
+	}
+	return checkTrailingData(dec, raw)
+}
+
+// checkTrailingData return bad request error if raw body has data
+// after decoded value
+func checkTrailingData(dec Decoder, raw []byte) error {
+	switch d := dec.(type) {
+	case *json.Decoder:
+		offset := d.InputOffset()
+		rest := bytes.TrimLeft(raw[offset:], " \t\r\n")
+		if len(rest) == 0 {
+			return nil
+		}
+		offset += int64(len(raw[offset:]) - len(rest))
+		return newStatusError(http.StatusBadRequest, "invalid_json",
+			fmt.Sprintf("Invalid JSON at offset %d: unexpected data after top-level value", offset)).
+			WithField("offset", offset)
+	case *xml.Decoder:
+		for {
+			tok, err := d.Token()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return decodeError(err)
+			}
+			switch t := tok.(type) {
+			case xml.Comment, xml.ProcInst:
+				continue
+			case xml.CharData:
+				if len(bytes.TrimSpace(t)) == 0 {
+					continue
+				}
+			}
+			return newStatusError(http.StatusBadRequest, "invalid_xml",
+				fmt.Sprintf("Invalid XML at offset %d: unexpected data after root element", d.InputOffset())).
+				WithField("offset", d.InputOffset())
+		}
+	}
+	var extra interface{}
+	if err := dec.Decode(&extra); err != io.EOF {
+		return newStatusError(http.StatusBadRequest, "invalid_body", "Invalid request body: unexpected data after value")
+	}
+	return nil
+}
+
+// decodeError return bad request error with position and field
+// which failed to decode
+func decodeError(err error) Error {
+	var (
+		syntaxErr    *json.SyntaxError
+		typeErr      *json.UnmarshalTypeError
+		xmlSyntaxErr *xml.SyntaxError
+	)
+	switch {
+	case errors.As(err, &syntaxErr):
+		return newStatusError(http.StatusBadRequest, "invalid_json",
+			fmt.Sprintf("Invalid JSON at offset %d: %s", syntaxErr.Offset, strings.TrimPrefix(err.Error(), "json: "))).
+			WithField("offset", syntaxErr.Offset).WithCause(err)
+	case errors.As(err, &typeErr):
+		e := newStatusError(http.StatusBadRequest, "invalid_field",
+			fmt.Sprintf("Invalid value of field %q at offset %d: %s is not %v", typeErr.Field, typeErr.Offset, typeErr.Value, typeErr.Type))
+		return e.WithDetails(map[string]interface{}{"field": typeErr.Field, "offset": typeErr.Offset}).WithCause(err)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return newStatusError(http.StatusBadRequest, "unknown_field", fmt.Sprintf("Unknown field %q", field)).
+			WithField("field", field).WithCause(err)
+	case errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF):
+		return newStatusError(http.StatusBadRequest, "invalid_body", "Unexpected end of request body").WithCause(err)
+	case errors.As(err, &xmlSyntaxErr):
+		return newStatusError(http.StatusBadRequest, "invalid_xml",
+			fmt.Sprintf("Invalid XML at line %d: %s", xmlSyntaxErr.Line, xmlSyntaxErr.Msg)).
+			WithField("line", xmlSyntaxErr.Line).WithCause(err)
+	}
+	return newStatusError(http.StatusBadRequest, "invalid_body", "Invalid request body: "+err.Error()).WithCause(err)
+}
+
+// limitedReader read at most n bytes and fail with 413 error on
+// larger body
+type limitedReader struct {
+	io.ReadCloser
+	n        int64
+	exceeded bool
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.exceeded {
+		return 0, NewRequestEntityTooLargeError()
+	}
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.ReadCloser.Read(p)
+	if int64(n) <= l.n {
+		l.n -= int64(n)
+		return n, err
+	}
+	n, l.n, l.exceeded = int(l.n), 0, true
+	return n, NewRequestEntityTooLargeError()
+}
@@ -0,0 +1,84 @@
+package restik
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type decodeArg struct {
+	Email string `json:"email" xml:"email"`
+	N     int    `json:"n" xml:"n"`
+}
+
+func TestDecodeTrailingData(t *testing.T) {
+	r := NewRouter()
+	r.RegisterCodec(XMLCodec)
+	r.Post("/post", func(a *decodeArg) (*decodeArg, error) { return a, nil })
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+		code        string
+	}{
+		{"json", "application/json", `{"email":"a@b.co","n":5}` + "\n", http.StatusOK, ""},
+		{"json second value", "application/json", `{"email":"a@b.co","n":5}{"email":"zz"}`, http.StatusBadRequest, `"offset":24`},
+		{"json garbage", "application/json", `{"email":"x"} garbage`, http.StatusBadRequest, `"offset":14`},
+		{"xml", "application/xml", "<a><email>x</email></a>\n<!-- end -->", http.StatusOK, ""},
+		{"xml second element", "application/xml", "<a><email>x</email></a><b/>", http.StatusBadRequest, `"code":"invalid_xml"`},
+		{"xml garbage", "application/xml", "<a><email>x</email></a> garbage", http.StatusBadRequest, `"code":"invalid_xml"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/post", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			req.Header.Set("Accept", "application/json")
+			w := httptest.NewRecorder()
+			r.Handler().ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body)
+			}
+			if tt.code != "" && !strings.Contains(w.Body.String(), tt.code) {
+				t.Errorf("body = %s, want %s", w.Body, tt.code)
+			}
+		})
+	}
+}
+
+func TestDecodeUnknownContentType(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		strict      bool
+		status      int
+	}{
+		{"form by default codec", "application/x-www-form-urlencoded", false, http.StatusOK},
+		{"unknown by default codec", "text/plain", false, http.StatusOK},
+		{"strict unknown", "text/plain", true, http.StatusUnsupportedMediaType},
+		{"strict empty", "", true, http.StatusUnsupportedMediaType},
+		{"strict json", "application/json", true, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRouter()
+			r.SetDecodeOptions(DecodeOptions{RequireContentType: tt.strict})
+			r.Post("/post", func(a *decodeArg) (*decodeArg, error) { return a, nil })
+
+			req := httptest.NewRequest("POST", "/post", strings.NewReader(`{"email":"a@b.co"}`))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			w := httptest.NewRecorder()
+			r.Handler().ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status == http.StatusOK && !strings.Contains(w.Body.String(), "a@b.co") {
+				t.Errorf("body = %s", w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package restik
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+)
+
+// StatusClientClosedRequest is non-standard status of request canceled by client
+const StatusClientClosedRequest = 499
+
+// ErrorMapper convert errors returned by handlers to Error.
+// Restik errors anywhere in chain of wrapped errors keep their status,
+// other errors are converted by mappings, errors without mapping become
+// internal errors with generic message and original error as cause
+type ErrorMapper struct {
+	mappings []func(error) Error
+}
+
+var defaultErrorMapper = NewErrorMapper()
+
+// NewErrorMapper return mapper with default mappings
+//
+//	context.DeadlineExceeded - 504 Gateway Timeout
+//	context.Canceled         - 499 Client Closed Request
+//	sql.ErrNoRows            - 404 Not Found
+func NewErrorMapper() *ErrorMapper {
+	m := &ErrorMapper{}
+	m.Map(sql.ErrNoRows, NewNotFoundError())
+	m.Map(context.Canceled, NewError(StatusClientClosedRequest, "client_closed_request", "Client closed request"))
+	m.Map(context.DeadlineExceeded, NewGatewayTimeoutError())
+	return m
+}
+
+// Map add mapping of errors matching target by errors.Is to e.
+// Mappings added later have priority
+func (m *ErrorMapper) Map(target error, e Error) *ErrorMapper {
+	return m.MapFunc(func(err error) Error {
+		if errors.Is(err, target) {
+			return Detailed(e).WithCause(err)
+		}
+		return nil
+	})
+}
+
+// MapFunc add mapping function. Function return nil for errors
+// which it does not convert. Mappings added later have priority
+func (m *ErrorMapper) MapFunc(f func(error) Error) *ErrorMapper {
+	m.mappings = append(m.mappings, f)
+	return m
+}
+
+// MapError convert err to Error
+func (m *ErrorMapper) MapError(err error) Error {
+	if err == nil {
+		return nil
+	}
+	var e Error
+	if errors.As(err, &e) {
+		return e
+	}
+	for i := len(m.mappings) - 1; i >= 0; i-- {
+		if e := m.mappings[i](err); e != nil {
+			return e
+		}
+	}
+	return newStatusError(http.StatusInternalServerError).WithCause(err)
+}
+
+// SetErrorMapper set mapper of errors returned by handlers
+func (r *Router) SetErrorMapper(m *ErrorMapper) {
+	r.errorMapper = m
+}
+
+// MapError add mapping of errors matching target by errors.Is to e
+//
+// Using:
+//
+//	r.MapError(ErrUserNotFound, restik.NewNotFoundError("user_not_found", "User not found"))
+func (r *Router) MapError(target error, e Error) *Router {
+	if r.errorMapper == nil {
+		r.errorMapper = NewErrorMapper()
+	}
+	r.errorMapper.Map(target, e)
+	return r
+}
@@ -0,0 +1,52 @@
+package restik
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var errQuota = errors.New("quota exceeded")
+
+func TestRouterMapperInReply(t *testing.T) {
+	tests := []struct {
+		name    string
+		accept  string
+		handler interface{}
+	}{
+		{"rest handler", "", func(w ResponseWriter, r *Request) {
+			rpl := r.Route.router.replyImpl.New()
+			rpl.SetError(errQuota)
+			w.WriteReply(rpl)
+		}},
+		{"rest handler problem", "application/problem+json", func(w ResponseWriter, r *Request) {
+			w.WriteError(errQuota)
+		}},
+		{"returned error", "", func() (string, error) {
+			return "", fmt.Errorf("check: %w", errQuota)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRouter()
+			r.MapError(errQuota, NewError(http.StatusTooManyRequests, "quota_exceeded", "Quota exceeded"))
+			r.Get("/q", tt.handler)
+
+			req := httptest.NewRequest("GET", "/q", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			r.Handler().ServeHTTP(w, req)
+			if w.Code != http.StatusTooManyRequests {
+				t.Errorf("status = %d, want 429", w.Code)
+			}
+			if !strings.Contains(w.Body.String(), `"status":429`) || !strings.Contains(w.Body.String(), "quota_exceeded") {
+				t.Errorf("body = %s", w.Body)
+			}
+		})
+	}
+}
@@ -10,6 +10,48 @@ var (
 	ErrNotFoundEndpoint = NewError(404, "endpoint_not_found", "Endpoint not found")
 )
 
+// Sentinel errors with default codes. errors.Is(err, ErrNotFound) report
+// whether err has NotFound status with any code
+var (
+	ErrBadRequest            = NewBadRequestError()
+	ErrUnauthorized          = NewUnauthorizedError()
+	ErrForbidden             = NewForbiddenError()
+	ErrNotFound              = NewNotFoundError()
+	ErrMethodNotAllowed      = NewMethodNotAllowedError()
+	ErrNotAcceptable         = NewNotAcceptableError()
+	ErrConflict              = NewConflictError()
+	ErrGone                  = NewGoneError()
+	ErrPreconditionFailed    = NewPreconditionFailedError()
+	ErrRequestEntityTooLarge = NewRequestEntityTooLargeError()
+	ErrUnsupportedMediaType  = NewUnsupportedMediaTypeError()
+	ErrUnprocessableEntity   = NewUnprocessableEntityError()
+	ErrTooManyRequests       = NewTooManyRequestsError()
+	ErrInternal              = NewInternalError()
+	ErrServiceUnavailable    = NewServiceUnavailableError()
+	ErrGatewayTimeout        = NewGatewayTimeoutError()
+)
+
+// statusDefaults is default codes and messages of error statuses
+var statusDefaults = map[int]struct{ code, msg string }{
+	http.StatusBadRequest:            {"bad_request", "Bad request"},
+	http.StatusUnauthorized:          {"unauthorized", "Unauthorized"},
+	http.StatusForbidden:             {"forbidden", "Forbidden"},
+	http.StatusNotFound:              {"not_found", "Not found"},
+	http.StatusMethodNotAllowed:      {"not_allowed", "Method not allowed"},
+	http.StatusNotAcceptable:         {"not_acceptable", "Not acceptable"},
+	http.StatusConflict:              {"conflict", "Conflict"},
+	http.StatusGone:                  {"gone", "Gone"},
+	http.StatusPreconditionFailed:    {"precondition_failed", "Precondition failed"},
+	http.StatusRequestEntityTooLarge: {"request_entity_too_large", "Request entity too large"},
+	http.StatusUnsupportedMediaType:  {"unsupported_media_type", "Unsupported media type"},
+	http.StatusUnprocessableEntity:   {"unprocessable_entity", "Unprocessable entity"},
+	http.StatusTooManyRequests:       {"too_many_requests", "Too many requests"},
+	http.StatusInternalServerError:   {"internal_error", "Internal server error"},
+	http.StatusServiceUnavailable:    {"service_unavailable", "Service unavailable"},
+	http.StatusGatewayTimeout:        {"gateway_timeout", "Gateway timeout"},
+}
+
+// Error is rest error with http status, code and message for client
 type Error interface {
 	Error() string
 	GetStatus() int
@@ -17,24 +59,72 @@ type Error interface {
 	GetMessage() string
 }
 
+// DetailedError is Error with cause and details. Cause is kept for logs
+// and errors.Is/As, details are sent to client. Errors of restik
+// constructors implement it, other errors are converted by Detailed
+type DetailedError interface {
+	Error
+	// Unwrap return cause of error
+	Unwrap() error
+	// WithCause return copy of error with cause
+	WithCause(cause error) DetailedError
+	// WithDetails return copy of error with details added
+	WithDetails(details map[string]interface{}) DetailedError
+	// WithField return copy of error with one detail added
+	WithField(key string, value interface{}) DetailedError
+}
+
+// Detailed return err as DetailedError. Error of other implementation
+// is copied with err as cause
+//
+// Using:
+//
+//	restik.Detailed(restik.NewNotFoundError()).WithCause(err).WithField("id", id)
+func Detailed(err Error) DetailedError {
+	if d, ok := err.(DetailedError); ok {
+		return d
+	}
+	return &errorImpl{Status: err.GetStatus(), Code: err.GetCode(), Msg: err.GetMessage(), cause: err}
+}
+
+// FieldError describe violation of argument field
+type FieldError struct {
+	Field string `json:"field" xml:"field"`
+	Rule  string `json:"rule" xml:"rule"`
+	Msg   string `json:"msg" xml:"msg"`
+}
+
 // Error - rest errors
 type errorImpl struct {
-	Status int    `json:"status"`
-	Code   string `json:"code"`
-	Msg    string `json:"msg"`
+	Status  int                    `json:"status" xml:"status"`
+	Code    string                 `json:"code" xml:"code"`
+	Msg     string                 `json:"msg" xml:"msg"`
+	Fields  []FieldError           `json:"fields,omitempty" xml:"fields>field,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty" xml:"-"`
+	cause   error
 }
 
 // NewError return new Error instance
 func NewError(status int, code, msg string) Error {
-	return &errorImpl{status, code, msg}
+	return &errorImpl{Status: status, Code: code, Msg: msg}
 }
 
-// FromAnotherError wrap any error to Error
-func FromAnotherError(err error) Error {
-	if e, ok := err.(Error); ok {
-		return e
+// NewValidationError return error with UnprocessableEntity status
+// and list of fields violations
+func NewValidationError(fields ...FieldError) Error {
+	return &errorImpl{
+		Status: http.StatusUnprocessableEntity,
+		Code:   "validation_failed",
+		Msg:    "Validation failed",
+		Fields: fields,
 	}
-	return NewBadRequestError(err.Error())
+}
+
+// FromAnotherError wrap any error to Error by default ErrorMapper.
+// Error found in chain of wrapped errors is returned as is, unknown
+// errors become internal errors
+func FromAnotherError(err error) Error {
+	return defaultErrorMapper.MapError(err)
 }
 
 // NewBadRequestError return error with BadRequest status
@@ -46,10 +136,34 @@ func FromAnotherError(err error) Error {
 // or
 //		NewBadRequestError(code, message)
 func NewBadRequestError(args ...string) Error {
-	return NewError(parseErrorArgs(http.StatusBadRequest, "bad_request", "Bad request", args...))
+	return newStatusError(http.StatusBadRequest, args...)
+}
+
+// NewUnauthorizedError return error with Unauthorized status
+//
+// Using:
+//		NewUnauthorizedError()
+// or
+//		NewUnauthorizedError(message)
+// or
+//		NewUnauthorizedError(code, message)
+func NewUnauthorizedError(args ...string) Error {
+	return newStatusError(http.StatusUnauthorized, args...)
+}
+
+// NewForbiddenError return error with Forbidden status
+//
+// Using:
+//		NewForbiddenError()
+// or
+//		NewForbiddenError(message)
+// or
+//		NewForbiddenError(code, message)
+func NewForbiddenError(args ...string) Error {
+	return newStatusError(http.StatusForbidden, args...)
 }
 
-// NewNotFoundError return error with BadRequest status
+// NewNotFoundError return error with NotFound status
 //
 // Using:
 //		NewNotFoundError()
@@ -58,10 +172,118 @@ func NewBadRequestError(args ...string) Error {
 // or
 //		NewNotFoundError(code, message)
 func NewNotFoundError(args ...string) Error {
-	return NewError(parseErrorArgs(http.StatusNotFound, "not_found", "Not found", args...))
+	return newStatusError(http.StatusNotFound, args...)
+}
+
+// NewMethodNotAllowedError return error with MethodNotAllowed status
+//
+// Using:
+//		NewMethodNotAllowedError()
+// or
+//		NewMethodNotAllowedError(message)
+// or
+//		NewMethodNotAllowedError(code, message)
+func NewMethodNotAllowedError(args ...string) Error {
+	return newStatusError(http.StatusMethodNotAllowed, args...)
+}
+
+// NewNotAcceptableError return error with NotAcceptable status
+//
+// Using:
+//		NewNotAcceptableError()
+// or
+//		NewNotAcceptableError(message)
+// or
+//		NewNotAcceptableError(code, message)
+func NewNotAcceptableError(args ...string) Error {
+	return newStatusError(http.StatusNotAcceptable, args...)
+}
+
+// NewConflictError return error with Conflict status
+//
+// Using:
+//		NewConflictError()
+// or
+//		NewConflictError(message)
+// or
+//		NewConflictError(code, message)
+func NewConflictError(args ...string) Error {
+	return newStatusError(http.StatusConflict, args...)
 }
 
-// NewInternalError return error with BadRequest status
+// NewGoneError return error with Gone status
+//
+// Using:
+//		NewGoneError()
+// or
+//		NewGoneError(message)
+// or
+//		NewGoneError(code, message)
+func NewGoneError(args ...string) Error {
+	return newStatusError(http.StatusGone, args...)
+}
+
+// NewPreconditionFailedError return error with PreconditionFailed status
+//
+// Using:
+//		NewPreconditionFailedError()
+// or
+//		NewPreconditionFailedError(message)
+// or
+//		NewPreconditionFailedError(code, message)
+func NewPreconditionFailedError(args ...string) Error {
+	return newStatusError(http.StatusPreconditionFailed, args...)
+}
+
+// NewRequestEntityTooLargeError return error with RequestEntityTooLarge status
+//
+// Using:
+//		NewRequestEntityTooLargeError()
+// or
+//		NewRequestEntityTooLargeError(message)
+// or
+//		NewRequestEntityTooLargeError(code, message)
+func NewRequestEntityTooLargeError(args ...string) Error {
+	return newStatusError(http.StatusRequestEntityTooLarge, args...)
+}
+
+// NewUnsupportedMediaTypeError return error with UnsupportedMediaType status
+//
+// Using:
+//		NewUnsupportedMediaTypeError()
+// or
+//		NewUnsupportedMediaTypeError(message)
+// or
+//		NewUnsupportedMediaTypeError(code, message)
+func NewUnsupportedMediaTypeError(args ...string) Error {
+	return newStatusError(http.StatusUnsupportedMediaType, args...)
+}
+
+// NewUnprocessableEntityError return error with UnprocessableEntity status
+//
+// Using:
+//		NewUnprocessableEntityError()
+// or
+//		NewUnprocessableEntityError(message)
+// or
+//		NewUnprocessableEntityError(code, message)
+func NewUnprocessableEntityError(args ...string) Error {
+	return newStatusError(http.StatusUnprocessableEntity, args...)
+}
+
+// NewTooManyRequestsError return error with TooManyRequests status
+//
+// Using:
+//		NewTooManyRequestsError()
+// or
+//		NewTooManyRequestsError(message)
+// or
+//		NewTooManyRequestsError(code, message)
+func NewTooManyRequestsError(args ...string) Error {
+	return newStatusError(http.StatusTooManyRequests, args...)
+}
+
+// NewInternalError return error with InternalServerError status
 //
 // Using:
 //		NewInternalError()
@@ -70,14 +292,84 @@ func NewNotFoundError(args ...string) Error {
 // or
 //		NewInternalError(code, message)
 func NewInternalError(args ...string) Error {
-	return NewError(parseErrorArgs(http.StatusInternalServerError, "internal_error", "Intertal Server Error", args...))
+	return newStatusError(http.StatusInternalServerError, args...)
 }
 
-// Error implement error interface
+// NewServiceUnavailableError return error with ServiceUnavailable status
+//
+// Using:
+//		NewServiceUnavailableError()
+// or
+//		NewServiceUnavailableError(message)
+// or
+//		NewServiceUnavailableError(code, message)
+func NewServiceUnavailableError(args ...string) Error {
+	return newStatusError(http.StatusServiceUnavailable, args...)
+}
+
+// NewGatewayTimeoutError return error with GatewayTimeout status
+//
+// Using:
+//		NewGatewayTimeoutError()
+// or
+//		NewGatewayTimeoutError(message)
+// or
+//		NewGatewayTimeoutError(code, message)
+func NewGatewayTimeoutError(args ...string) Error {
+	return newStatusError(http.StatusGatewayTimeout, args...)
+}
+
+// Error implement error interface. Message of cause is included
 func (err errorImpl) Error() string {
+	if err.cause != nil && err.cause.Error() != err.Msg {
+		return fmt.Sprintf("[%d] %s: %s", err.Status, err.Msg, err.cause)
+	}
 	return fmt.Sprintf("[%d] %s", err.Status, err.Msg)
 }
 
+// Unwrap return cause of error
+func (err errorImpl) Unwrap() error {
+	return err.cause
+}
+
+// WithCause return copy of error with cause
+func (err errorImpl) WithCause(cause error) DetailedError {
+	err.cause = cause
+	return &err
+}
+
+// WithDetails return copy of error with details added
+func (err errorImpl) WithDetails(details map[string]interface{}) DetailedError {
+	merged := make(map[string]interface{}, len(err.Details)+len(details))
+	for k, v := range err.Details {
+		merged[k] = v
+	}
+	for k, v := range details {
+		merged[k] = v
+	}
+	err.Details = merged
+	return &err
+}
+
+// WithField return copy of error with one detail added
+func (err errorImpl) WithField(key string, value interface{}) DetailedError {
+	return err.WithDetails(map[string]interface{}{key: value})
+}
+
+// Is report whether target is Error with the same status and code.
+// Target with default code of its status match any code
+func (err errorImpl) Is(target error) bool {
+	t, ok := target.(Error)
+	if !ok || t.GetStatus() != err.Status {
+		return false
+	}
+	if t.GetCode() == err.Code {
+		return true
+	}
+	def, ok := statusDefaults[t.GetStatus()]
+	return ok && t.GetCode() == def.code
+}
+
 // GetStatus return http status
 func (err errorImpl) GetStatus() int {
 	return err.Status
@@ -93,6 +385,23 @@ func (err errorImpl) GetMessage() string {
 	return err.Msg
 }
 
+// GetFields return list of fields violations
+func (err errorImpl) GetFields() []FieldError {
+	return err.Fields
+}
+
+// GetDetails return error details
+func (err errorImpl) GetDetails() map[string]interface{} {
+	return err.Details
+}
+
+// newStatusError return error with status and its default code and message
+func newStatusError(status int, args ...string) *errorImpl {
+	def := statusDefaults[status]
+	status, code, msg := parseErrorArgs(status, def.code, def.msg, args...)
+	return &errorImpl{Status: status, Code: code, Msg: msg}
+}
+
 // using:
 //		parseErrorArgs(status, defaultCode, defaultMsg)
 // or
@@ -108,3 +417,4 @@ func parseErrorArgs(status int, defaultCode, defaultMsg string, args ...string)
 	}
 	return status, code, msg
 }
+
@@ -0,0 +1,48 @@
+package restik
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// quotaError is third-party implementation of Error
+type quotaError struct{}
+
+func (quotaError) Error() string      { return "quota" }
+func (quotaError) GetStatus() int     { return http.StatusTooManyRequests }
+func (quotaError) GetCode() string    { return "quota" }
+func (quotaError) GetMessage() string { return "Quota exceeded" }
+
+var _ Error = quotaError{}
+
+func TestThirdPartyError(t *testing.T) {
+	r := NewRouter()
+	r.Get("/q", func() (string, error) {
+		return "", fmt.Errorf("wrapped: %w", quotaError{})
+	})
+	w := httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/q", nil))
+	// own Error implementations are encoded as they are
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("status = %d, body %s", w.Code, w.Body)
+	}
+
+	d := Detailed(quotaError{}).WithField("limit", 10)
+	if d.GetStatus() != http.StatusTooManyRequests || !errors.Is(d, quotaError{}) {
+		t.Errorf("Detailed lost status or cause: %v", d)
+	}
+}
+
+func TestDetailedError(t *testing.T) {
+	cause := errors.New("no rows")
+	e := Detailed(NewNotFoundError("user_not_found", "User not found")).WithCause(cause).WithField("id", 7)
+	if !errors.Is(e, cause) || !errors.Is(e, ErrNotFound) {
+		t.Errorf("errors.Is failed for %v", e)
+	}
+	if d := e.(interface{ GetDetails() map[string]interface{} }).GetDetails(); d["id"] != 7 {
+		t.Errorf("details = %v", d)
+	}
+}
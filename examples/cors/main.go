@@ -2,6 +2,7 @@ package main
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/vettich/restik"
 )
@@ -13,9 +14,11 @@ func hello() string {
 func main() {
 	r := restik.NewRouter()
 	r.Use(&restik.CorsMiddleware{
-		AllowedMethods: []string{"GET", "OPTIONS"},
-		AllowedHeaders: []string{"Content-Type", "Origin"},
-		AllowedOrigin:  "*",
+		AllowedOrigins:   []string{"http://localhost:8080", "https://*.example.com"},
+		AllowedMethods:   []string{"GET", "POST"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		AllowCredentials: true,
+		MaxAge:           time.Hour,
 	})
 	r.Get("/hello", hello)
 	http.Handle("/", r.Handler())
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"errors"
 	"log"
 	"net/http"
 
@@ -18,7 +17,7 @@ type helloArg struct {
 
 func hello(arg helloArg) (string, error) {
 	if arg.Value == "" {
-		return "", errors.New("value is empty")
+		return "", restik.NewBadRequestError("value is empty")
 	}
 	return "hello, " + arg.Value, nil
 }
@@ -0,0 +1,108 @@
+package restik
+
+import (
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// File is handler result served as file. It is written without reply
+// envelope with Range, If-Modified-Since and ETag support of
+// http.ServeContent. Handlers can return plain io.ReadSeeker too,
+// name and modification time of *os.File are taken from its info
+//
+// Using:
+//
+//	func download(arg *downloadArg) (*restik.File, error) {
+//		f, err := os.Open(path(arg.ID))
+//		if err != nil {
+//			return nil, err
+//		}
+//		return &restik.File{Name: "report.pdf", Content: f}, nil
+//	}
+type File struct {
+	// Name is file name in Content-Disposition header. Content type
+	// is detected by its extension if ContentType is empty
+	Name        string
+	ContentType string
+	ModTime     time.Time
+	// Content is closed after serving if it is io.Closer
+	Content io.ReadSeeker
+	ETag    string
+	// Inline serve file with inline disposition instead of attachment
+	Inline bool
+}
+
+var (
+	fileType       = reflect.TypeOf(File{})
+	readSeekerType = reflect.TypeOf((*io.ReadSeeker)(nil)).Elem()
+)
+
+// servesFile report whether route handler return file
+func (rt *Route) servesFile() bool {
+	return rt.reply != nil && (rt.reply == fileType || rt.reply.Implements(readSeekerType) ||
+		reflect.PtrTo(rt.reply).Implements(readSeekerType))
+}
+
+// writeFile serve file result of handler and report whether resp is file
+func writeFile(rw ResponseWriter, rr *Request, resp interface{}) bool {
+	var f File
+	switch v := resp.(type) {
+	case File:
+		f = v
+	case *File:
+		if v == nil {
+			return false
+		}
+		f = *v
+	case io.ReadSeeker:
+		f = File{Content: v}
+		if s, ok := v.(interface{ Stat() (fs.FileInfo, error) }); ok {
+			if info, err := s.Stat(); err == nil {
+				f.Name = info.Name()
+				f.ModTime = info.ModTime()
+			}
+		}
+	default:
+		return false
+	}
+	if f.Content == nil {
+		rw.WriteError(NewNotFoundError())
+		return true
+	}
+	if c, ok := f.Content.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	header := rw.Header()
+	if f.ContentType != "" {
+		header.Set("Content-Type", f.ContentType)
+	}
+	if f.ETag != "" {
+		header.Set("ETag", quoteETag(f.ETag))
+	}
+	if f.Name != "" {
+		disposition := "attachment"
+		if f.Inline {
+			disposition = "inline"
+		}
+		if cd := mime.FormatMediaType(disposition, map[string]string{"filename": filepath.Base(f.Name)}); cd != "" {
+			header.Set("Content-Disposition", cd)
+		}
+	}
+	http.ServeContent(rw.ResponseWriter, rr.Request, f.Name, f.ModTime, f.Content)
+	return true
+}
+
+// quoteETag return entity tag in quotes required by RFC 7232
+func quoteETag(etag string) string {
+	if strings.HasPrefix(etag, `"`) || strings.HasPrefix(etag, `W/"`) {
+		return etag
+	}
+	return `"` + etag + `"`
+}
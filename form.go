@@ -0,0 +1,148 @@
+package restik
+
+import (
+	"mime"
+	"mime/multipart"
+	"reflect"
+)
+
+// defaultMaxMemory is size of multipart form parts stored in memory,
+// other parts are stored in temporary files
+const defaultMaxMemory = 32 << 20
+
+// Media types of forms
+const (
+	MultipartFormMediaType  = "multipart/form-data"
+	URLEncodedFormMediaType = "application/x-www-form-urlencoded"
+)
+
+// UploadedFile is file of multipart form bound to argument field
+// with form tag
+//
+// Using:
+//
+//	type uploadArg struct {
+//		Title  string                `form:"title"`
+//		Avatar *multipart.FileHeader `form:"avatar" validate:"required"`
+//		Photos []restik.UploadedFile `form:"photos"`
+//	}
+type UploadedFile struct {
+	Filename    string
+	ContentType string
+	Size        int64
+	Header      *multipart.FileHeader `json:"-"`
+}
+
+// Open open content of file. Temporary files are removed after
+// handler returns
+func (f *UploadedFile) Open() (multipart.File, error) {
+	return f.Header.Open()
+}
+
+func newUploadedFile(fh *multipart.FileHeader) UploadedFile {
+	return UploadedFile{
+		Filename:    fh.Filename,
+		ContentType: fh.Header.Get("Content-Type"),
+		Size:        fh.Size,
+		Header:      fh,
+	}
+}
+
+var (
+	fileHeaderType   = reflect.TypeOf((*multipart.FileHeader)(nil))
+	uploadedFileType = reflect.TypeOf(UploadedFile{})
+)
+
+// isFileType report whether form field of type t is bound from files
+func isFileType(t reflect.Type) bool {
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Ptr && t != fileHeaderType {
+		t = t.Elem()
+	}
+	return t == fileHeaderType || t == uploadedFileType
+}
+
+// setFileField fill field of file type from uploaded files
+func setFileField(field reflect.Value, files []*multipart.FileHeader) {
+	t := field.Type()
+	if t.Kind() == reflect.Slice {
+		slice := reflect.MakeSlice(t, len(files), len(files))
+		for i, fh := range files {
+			setFileValue(slice.Index(i), fh)
+		}
+		field.Set(slice)
+		return
+	}
+	setFileValue(field, files[0])
+}
+
+func setFileValue(v reflect.Value, fh *multipart.FileHeader) {
+	switch v.Type() {
+	case fileHeaderType:
+		v.Set(reflect.ValueOf(fh))
+	case uploadedFileType:
+		v.Set(reflect.ValueOf(newUploadedFile(fh)))
+	default:
+		f := newUploadedFile(fh)
+		v.Set(reflect.ValueOf(&f))
+	}
+}
+
+// MaxMemory set size of multipart form stored in memory, other parts
+// are stored in temporary files. Default is 32 MB
+func (rt *Route) MaxMemory(n int64) *Route {
+	rt.maxMemory = n
+	return rt
+}
+
+// MaxUploadSize set limit of form body size. Requests with larger
+// body are answered with 413 status. Default is MaxBodySize of decode
+// options
+func (rt *Route) MaxUploadSize(n int64) *Route {
+	rt.maxUploadSize = n
+	return rt
+}
+
+// parseForm parse multipart or urlencoded body of request and report
+// whether request has form body
+func (rt *Route) parseForm(rr *Request) (bool, error) {
+	mt, _, _ := mime.ParseMediaType(rr.Header.Get("Content-Type"))
+	if mt != MultipartFormMediaType && mt != URLEncodedFormMediaType {
+		return false, nil
+	}
+	maxSize := rt.maxUploadSize
+	if maxSize <= 0 {
+		maxSize = rt.getDecodeOptions().MaxBodySize
+	}
+	var body *limitedReader
+	if maxSize > 0 {
+		body = &limitedReader{ReadCloser: rr.Body, n: maxSize}
+		rr.Body = body
+	}
+	var err error
+	if mt == MultipartFormMediaType {
+		maxMemory := rt.maxMemory
+		if maxMemory <= 0 {
+			maxMemory = defaultMaxMemory
+		}
+		err = rr.ParseMultipartForm(maxMemory)
+	} else {
+		err = rr.ParseForm()
+	}
+	if err != nil {
+		if body != nil && body.exceeded {
+			return true, NewRequestEntityTooLargeError()
+		}
+		return true, NewBadRequestError("invalid_form", "Invalid form: "+err.Error())
+	}
+	return true, nil
+}
+
+// removeMultipartForm remove temporary files of multipart form
+func removeMultipartForm(rr *Request) {
+	if rr.MultipartForm != nil {
+		rr.MultipartForm.RemoveAll()
+	}
+}
@@ -0,0 +1,127 @@
+package restik
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+type formArg struct {
+	Title  string                `form:"title"`
+	Avatar *multipart.FileHeader `form:"avatar"`
+	Photos []UploadedFile        `form:"photos"`
+}
+
+// multipartBody return multipart body with title field and files
+// of given names and content
+func multipartBody(t *testing.T, files map[string]string) (*bytes.Buffer, string) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	if err := mw.WriteField("title", "hello"); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		fw, err := mw.CreateFormFile(name, name+".txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(fw, content)
+	}
+	mw.Close()
+	return &buf, mw.FormDataContentType()
+}
+
+func TestFormUpload(t *testing.T) {
+	var got formArg
+	var avatar string
+	r := NewRouter()
+	r.Post("/upload", func(a formArg) (string, error) {
+		got = a
+		f, err := a.Avatar.Open()
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		b, err := io.ReadAll(f)
+		avatar = string(b)
+		return "ok", err
+	})
+
+	body, contentType := multipartBody(t, map[string]string{"avatar": "face", "photos": "sea"})
+	req := httptest.NewRequest("POST", "/upload", body)
+	req.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	if got.Title != "hello" || avatar != "face" {
+		t.Errorf("title = %q, avatar = %q", got.Title, avatar)
+	}
+	if len(got.Photos) != 1 || got.Photos[0].Filename != "photos.txt" || got.Photos[0].Size != 3 {
+		t.Errorf("photos = %+v", got.Photos)
+	}
+}
+
+func TestFormMaxUploadSize(t *testing.T) {
+	r := NewRouter()
+	r.Post("/upload", func(a formArg) (string, error) { return "ok", nil }).MaxUploadSize(1024)
+
+	tests := []struct {
+		name   string
+		size   int
+		status int
+	}{
+		{"within limit", 100, http.StatusOK},
+		{"too large", 4096, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, contentType := multipartBody(t, map[string]string{"avatar": strings.Repeat("x", tt.size)})
+			req := httptest.NewRequest("POST", "/upload", body)
+			req.Header.Set("Content-Type", contentType)
+			w := httptest.NewRecorder()
+			r.Handler().ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d, body %s", w.Code, tt.status, w.Body)
+			}
+		})
+	}
+}
+
+func TestFormTempFilesRemoved(t *testing.T) {
+	var name string
+	r := NewRouter()
+	// files larger than MaxMemory are stored in temporary files
+	r.Post("/upload", func(a formArg) (string, error) {
+		f, err := a.Avatar.Open()
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		if file, ok := f.(*os.File); ok {
+			name = file.Name()
+		}
+		return "ok", nil
+	}).MaxMemory(1)
+
+	body, contentType := multipartBody(t, map[string]string{"avatar": strings.Repeat("x", 4096)})
+	req := httptest.NewRequest("POST", "/upload", body)
+	req.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	if name == "" {
+		t.Fatal("avatar is not stored in temporary file")
+	}
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Errorf("temporary file %s is not removed: %v", name, err)
+	}
+}
@@ -1,6 +1,6 @@
 module github.com/vettich/restik
 
-go 1.16
+go 1.18
 
 require (
 	github.com/gorilla/mux v1.8.0
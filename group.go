@@ -0,0 +1,116 @@
+package restik
+
+// Group is a set of routes with common path prefix and middlewares.
+// Group middlewares run after the router middlewares and only for
+// routes registered through the group
+type Group struct {
+	router      *Router
+	parent      *Group
+	prefix      string
+	middlewares middlewareList
+}
+
+// Group create new routes group with path prefix and middlewares
+func (r *Router) Group(prefix string, middlewares ...Middleware) *Group {
+	g := &Group{router: r, prefix: prefix}
+	g.middlewares.add(middlewares...)
+	return g
+}
+
+// Group create nested routes group. Prefix is appended to parent group
+// prefix, middlewares run after parent group middlewares
+func (g *Group) Group(prefix string, middlewares ...Middleware) *Group {
+	child := &Group{router: g.router, parent: g, prefix: g.prefix + prefix}
+	child.middlewares.add(middlewares...)
+	return child
+}
+
+// Prefix return full path prefix of group
+func (g *Group) Prefix() string {
+	return g.prefix
+}
+
+// Add add new routes to group. Route endpoint is prefixed with group prefix
+func (g *Group) Add(rts ...*Route) *Group {
+	for _, rt := range rts {
+		rt.Endpoint = g.prefix + rt.Endpoint
+		g.add(rt)
+	}
+	return g
+}
+
+// add add route with already prefixed endpoint to group
+func (g *Group) add(rt *Route) {
+	rt.group = g
+	g.router.Add(rt)
+}
+
+// Handle add new route with method to group and return.
+// It return error if fn has unsupported signature
+func (g *Group) Handle(method, endpoint string, fn interface{}) (*Route, error) {
+	rt, err := NewRouteE(method, g.prefix+endpoint, fn)
+	if err != nil {
+		return nil, err
+	}
+	g.add(rt)
+	return rt, nil
+}
+
+// newRoute add new route to group and return. Route is created with
+// prefixed endpoint, so signature errors name full endpoint
+func (g *Group) newRoute(method, endpoint string, fn interface{}) *Route {
+	rt := NewRoute(method, g.prefix+endpoint, fn)
+	g.add(rt)
+	return rt
+}
+
+// Get add new route with GET method to group and return
+func (g *Group) Get(endpoint string, fn interface{}) *Route {
+	return g.newRoute("GET", endpoint, fn)
+}
+
+// Post add new route with POST method to group and return
+func (g *Group) Post(endpoint string, fn interface{}) *Route {
+	return g.newRoute("POST", endpoint, fn)
+}
+
+// Delete add new route with DELETE method to group and return
+func (g *Group) Delete(endpoint string, fn interface{}) *Route {
+	return g.newRoute("DELETE", endpoint, fn)
+}
+
+// Patch add new route with PATCH method to group and return
+func (g *Group) Patch(endpoint string, fn interface{}) *Route {
+	return g.newRoute("PATCH", endpoint, fn)
+}
+
+// Put add new route with PUT method to group and return
+func (g *Group) Put(endpoint string, fn interface{}) *Route {
+	return g.newRoute("PUT", endpoint, fn)
+}
+
+// Use add middlewares to group
+func (g *Group) Use(middlewares ...Middleware) *Group {
+	g.middlewares.add(middlewares...)
+	g.router.invalidate()
+	return g
+}
+
+// UseFunc add middleware functions to group
+func (g *Group) UseFunc(middlewareFuncs ...func(HandlerFunc) HandlerFunc) *Group {
+	g.middlewares.add(funcMiddlewares(middlewareFuncs)...)
+	g.router.invalidate()
+	return g
+}
+
+// wrap wrap handler with middlewares of group and all its parents.
+// Parent group middlewares are outer
+func (g *Group) wrap(handle HandlerFunc) HandlerFunc {
+	for ; g != nil; g = g.parent {
+		middlewares := g.middlewares.get()
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			handle = middlewares[i].Middleware(handle)
+		}
+	}
+	return handle
+}
@@ -0,0 +1,33 @@
+package restik
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGroupRouteErrorNamesFullEndpoint(t *testing.T) {
+	g := NewRouter().Group("/api").Group("/v1")
+	_, err := g.Handle("GET", "/x", func(a, b, c int) {})
+	if err == nil || !strings.Contains(err.Error(), "GET /api/v1/x") {
+		t.Errorf("Handle error = %v", err)
+	}
+
+	defer func() {
+		rec := recover()
+		if err, ok := rec.(error); !ok || !strings.Contains(err.Error(), "POST /api/v1/y") {
+			t.Errorf("Post panic = %v", rec)
+		}
+	}()
+	g.Post("/y", 42)
+}
+
+func TestGroupRoutesArePrefixed(t *testing.T) {
+	g := NewRouter().Group("/api")
+	added := NewRoute("GET", "/b", helloHandler)
+	g.Add(added)
+	for _, rt := range []*Route{g.Get("/a", helloHandler), added} {
+		if !strings.HasPrefix(rt.Endpoint, "/api/") || strings.HasPrefix(rt.Endpoint, "/api/api") || rt.group != g {
+			t.Errorf("route %s %s", rt.Method, rt.Endpoint)
+		}
+	}
+}
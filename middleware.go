@@ -1,9 +1,11 @@
 package restik
 
 import (
+	"fmt"
 	"log"
 	"net/http"
-	"strings"
+	"runtime/debug"
+	"sync"
 )
 
 type HandlerFunc func(ResponseWriter, *Request)
@@ -12,25 +14,42 @@ type Middleware interface {
 	Middleware(next HandlerFunc) HandlerFunc
 }
 
-type CorsMiddleware struct {
-	AllowedMethods []string
-	AllowedHeaders []string
-	AllowedOrigin  string
+// Logger is used by middlewares for writing logs
+type Logger interface {
+	Printf(format string, v ...interface{})
 }
 
-func (mw *CorsMiddleware) Middleware(next HandlerFunc) HandlerFunc {
-	methods := strings.Join(mw.AllowedMethods, ",")
-	headers := strings.Join(mw.AllowedHeaders, ",")
+// RecoveryMiddleware recover panics in next handlers and answer with
+// internal error through the router reply
+type RecoveryMiddleware struct {
+	// Logger write panic value and stack, standard logger is used if nil
+	Logger Logger
+	// Debug add panic value and stack to error reply
+	Debug bool
+}
+
+func (mw *RecoveryMiddleware) Middleware(next HandlerFunc) HandlerFunc {
+	logger := mw.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
 	return func(w ResponseWriter, r *Request) {
-		log.Println(r.Method, r.URL.Path)
-		if r.Method == http.MethodOptions {
-			w.Header().Set("Access-Control-Allow-Methods", methods)
-			w.Header().Set("Access-Control-Allow-Headers", headers)
-			w.Header().Set("Access-Control-Allow-Origin", mw.AllowedOrigin)
-			w.WriteHeader(http.StatusNoContent)
-			return
-		}
-		w.Header().Set("Access-Control-Allow-Origin", mw.AllowedOrigin)
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+			stack := debug.Stack()
+			logger.Printf("panic: %v [%s %s]\n%s", rec, r.Method, r.URL.Path, stack)
+			if mw.Debug {
+				w.WriteError(newStatusError(http.StatusInternalServerError, fmt.Sprint("panic: ", rec)).WithField("stack", string(stack)))
+				return
+			}
+			w.WriteError(NewInternalError())
+		}()
 		next(w, r)
 	}
 }
@@ -42,3 +61,85 @@ type funcMiddleware struct {
 func (fm *funcMiddleware) Middleware(next HandlerFunc) HandlerFunc {
 	return fm.f(next)
 }
+
+// funcMiddlewares convert middleware functions to middlewares
+func funcMiddlewares(fs []func(HandlerFunc) HandlerFunc) []Middleware {
+	mws := make([]Middleware, len(fs))
+	for i, f := range fs {
+		mws[i] = &funcMiddleware{f}
+	}
+	return mws
+}
+
+// middlewareList is list of middlewares which can be added while
+// chains are built by serving goroutines. List is copied on adding,
+// so returned slices are never changed
+type middlewareList struct {
+	mu   sync.RWMutex
+	list []Middleware
+}
+
+// middlewareValidator is implemented by middlewares which validate
+// their configuration, e.g. CorsMiddleware
+type middlewareValidator interface {
+	Validate() error
+}
+
+// add append middlewares to copy of list. It panics if middleware
+// has invalid configuration, so it is reported on start instead of
+// on first request
+func (l *middlewareList) add(mws ...Middleware) {
+	for _, mw := range mws {
+		if v, ok := mw.(middlewareValidator); ok {
+			if err := v.Validate(); err != nil {
+				panic(err)
+			}
+		}
+	}
+	l.mu.Lock()
+	list := make([]Middleware, 0, len(l.list)+len(mws))
+	l.list = append(append(list, l.list...), mws...)
+	l.mu.Unlock()
+}
+
+// get return current middlewares
+func (l *middlewareList) get() []Middleware {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.list
+}
+
+// chainCache keep compiled middlewares chain until it is reset or
+// generation of middlewares is changed
+type chainCache struct {
+	mu     sync.RWMutex
+	gen    uint64
+	handle HandlerFunc
+}
+
+// get return compiled chain for generation gen. Chain is built by build
+// function on first call or after invalidation
+func (c *chainCache) get(gen uint64, build func() HandlerFunc) HandlerFunc {
+	c.mu.RLock()
+	handle := c.handle
+	valid := c.gen == gen
+	c.mu.RUnlock()
+	if handle != nil && valid {
+		return handle
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.handle == nil || c.gen != gen {
+		c.handle = build()
+		c.gen = gen
+	}
+	return c.handle
+}
+
+// reset drop compiled chain
+func (c *chainCache) reset() {
+	c.mu.Lock()
+	c.handle = nil
+	c.mu.Unlock()
+}
@@ -0,0 +1,133 @@
+package restik
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// headerMiddleware append name to X-Chain header of response
+func headerMiddleware(name string) func(HandlerFunc) HandlerFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(w ResponseWriter, r *Request) {
+			w.Header().Add("X-Chain", name)
+			next(w, r)
+		}
+	}
+}
+
+func helloHandler() (string, error) {
+	return "hello", nil
+}
+
+func BenchmarkServeHTTP(b *testing.B) {
+	benchmarks := []struct {
+		name  string
+		setup func(r *Router)
+		path  string
+	}{
+		{"NoMiddlewares", func(r *Router) {
+			r.Get("/hello", helloHandler)
+		}, "/hello"},
+		{"Router", func(r *Router) {
+			r.UseFunc(headerMiddleware("a"), headerMiddleware("b"))
+			r.Get("/hello", helloHandler)
+		}, "/hello"},
+		{"Group", func(r *Router) {
+			g := r.Group("/api", &funcMiddleware{headerMiddleware("g1")})
+			g.Group("/v1").UseFunc(headerMiddleware("g2")).Get("/hello", helloHandler)
+		}, "/api/v1/hello"},
+		{"Route", func(r *Router) {
+			r.Get("/hello", helloHandler).UseFunc(headerMiddleware("r1"), headerMiddleware("r2"))
+		}, "/hello"},
+		{"All", func(r *Router) {
+			r.UseFunc(headerMiddleware("a"), headerMiddleware("b"))
+			g := r.Group("/api").UseFunc(headerMiddleware("g"))
+			g.Get("/hello", helloHandler).UseFunc(headerMiddleware("r"))
+		}, "/api/hello"},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			r := NewRouter()
+			bm.setup(r)
+			h := r.Handler()
+			req := httptest.NewRequest("GET", bm.path, nil)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				h.ServeHTTP(httptest.NewRecorder(), req)
+			}
+		})
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	r := NewRouter()
+	r.UseFunc(headerMiddleware("router"))
+	g := r.Group("/api").UseFunc(headerMiddleware("group"))
+	g.Group("/v1").UseFunc(headerMiddleware("nested")).
+		Get("/hello", helloHandler).UseFunc(headerMiddleware("route"))
+
+	serve := func() string {
+		w := httptest.NewRecorder()
+		r.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/hello", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d", w.Code)
+		}
+		return strings.Join(w.Header().Values("X-Chain"), ",")
+	}
+	if got := serve(); got != "router,group,nested,route" {
+		t.Errorf("chain = %s", got)
+	}
+	// cached chains are rebuilt after Use
+	r.UseFunc(headerMiddleware("router2"))
+	g.UseFunc(headerMiddleware("group2"))
+	if got := serve(); got != "router,router2,group,group2,nested,route" {
+		t.Errorf("chain after Use = %s", got)
+	}
+}
+
+func TestUseWhileServing(t *testing.T) {
+	r := NewRouter()
+	g := r.Group("/api")
+	rt := g.Get("/hello", helloHandler)
+	h := r.Handler()
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	served := make(chan struct{}, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/hello", nil))
+				select {
+				case served <- struct{}{}:
+				default:
+				}
+			}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		<-served
+		r.UseFunc(headerMiddleware("router"))
+		g.UseFunc(headerMiddleware("group"))
+		rt.UseFunc(headerMiddleware("route"))
+	}
+	close(stop)
+	wg.Wait()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/api/hello", nil))
+	if n := len(w.Header().Values("X-Chain")); n != 60 {
+		t.Errorf("middlewares run = %d, want 60", n)
+	}
+}
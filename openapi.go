@@ -0,0 +1,587 @@
+package restik
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OpenAPI is OpenAPI 3.0 document
+type OpenAPI struct {
+	OpenAPI    string                     `json:"openapi"`
+	Info       OpenAPIInfo                `json:"info"`
+	Paths      map[string]OpenAPIPathItem `json:"paths"`
+	Components OpenAPIComponents          `json:"components"`
+}
+
+// OpenAPIInfo is metadata of API
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// OpenAPIPathItem is operations of one path by lowercase http method
+type OpenAPIPathItem map[string]*OpenAPIOperation
+
+// OpenAPIOperation describe one route
+type OpenAPIOperation struct {
+	OperationID string                      `json:"operationId,omitempty"`
+	Summary     string                      `json:"summary,omitempty"`
+	Description string                      `json:"description,omitempty"`
+	Tags        []string                    `json:"tags,omitempty"`
+	Parameters  []*OpenAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses"`
+}
+
+// OpenAPIParameter describe path, query or header parameter
+type OpenAPIParameter struct {
+	Name     string                       `json:"name"`
+	In       string                       `json:"in"`
+	Required bool                         `json:"required,omitempty"`
+	Schema   *Schema                      `json:"schema,omitempty"`
+	Content  map[string]*OpenAPIMediaType `json:"content,omitempty"`
+}
+
+// OpenAPIRequestBody describe request body
+type OpenAPIRequestBody struct {
+	Required bool                         `json:"required,omitempty"`
+	Content  map[string]*OpenAPIMediaType `json:"content"`
+}
+
+// OpenAPIResponse describe response
+type OpenAPIResponse struct {
+	Description string                       `json:"description"`
+	Content     map[string]*OpenAPIMediaType `json:"content,omitempty"`
+}
+
+// OpenAPIMediaType is schema of content
+type OpenAPIMediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// OpenAPIComponents is reusable schemas
+type OpenAPIComponents struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+// Schema is JSON schema of OpenAPI document
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
+
+const jsonMediaType = "application/json"
+
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+// Summary set summary of route for OpenAPI document
+func (rt *Route) Summary(summary string) *Route {
+	rt.summary = summary
+	return rt
+}
+
+// Description set description of route for OpenAPI document
+func (rt *Route) Description(description string) *Route {
+	rt.description = description
+	return rt
+}
+
+// Tags add tags of route for OpenAPI document
+func (rt *Route) Tags(tags ...string) *Route {
+	rt.tags = append(rt.tags, tags...)
+	return rt
+}
+
+// SetOpenAPIInfo set info of OpenAPI document
+func (r *Router) SetOpenAPIInfo(info OpenAPIInfo) {
+	r.openAPIInfo = info
+}
+
+// OpenAPI return OpenAPI 3.0 document of registered routes
+func (r *Router) OpenAPI() *OpenAPI {
+	info := r.openAPIInfo
+	if info.Title == "" {
+		info.Title = "API"
+	}
+	if info.Version == "" {
+		info.Version = "1.0.0"
+	}
+	doc := &OpenAPI{
+		OpenAPI: "3.0.3",
+		Info:    info,
+		Paths:   map[string]OpenAPIPathItem{},
+	}
+	gen := &openAPIGenerator{
+		schemas: newSchemaRegistry(),
+	}
+	_, gen.envelope = r.replyImpl.(*serveReply)
+	for _, c := range r.codecs {
+		gen.mediaTypes = append(gen.mediaTypes, mediaType(c))
+	}
+
+	keys := make([]string, 0, len(r.routes))
+	for key := range r.routes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		rt := r.routes[key]
+		if rt.implicit {
+			continue
+		}
+		path, params := parsePathTemplate(rt.Endpoint)
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = OpenAPIPathItem{}
+			doc.Paths[path] = item
+		}
+		item[strings.ToLower(rt.Method)] = gen.operation(rt, params)
+	}
+
+	doc.Components.Schemas = gen.schemas.schemas
+	return doc
+}
+
+// OpenAPIHandler return handler serving OpenAPI document as JSON
+//
+// Using:
+//
+//	r.Get("/openapi.json", r.OpenAPIHandler())
+func (r *Router) OpenAPIHandler() func(ResponseWriter, *Request) {
+	return func(w ResponseWriter, _ *Request) {
+		w.WriteJSON(r.OpenAPI())
+	}
+}
+
+// openAPIGenerator build operations of routes
+type openAPIGenerator struct {
+	schemas    *schemaRegistry
+	envelope   bool
+	mediaTypes []string
+}
+
+// content return content of schema in all media types of router codecs
+func (gen *openAPIGenerator) content(schema *Schema) map[string]*OpenAPIMediaType {
+	content := map[string]*OpenAPIMediaType{}
+	for _, mt := range gen.mediaTypes {
+		content[mt] = &OpenAPIMediaType{schema}
+	}
+	return content
+}
+
+func (gen *openAPIGenerator) operation(rt *Route, pathParams []*OpenAPIParameter) *OpenAPIOperation {
+	schemas, envelope := gen.schemas, gen.envelope
+	op := &OpenAPIOperation{
+		OperationID: rt.name,
+		Summary:     rt.summary,
+		Description: rt.description,
+		Tags:        rt.tags,
+		Parameters:  pathParams,
+		Responses:   map[string]*OpenAPIResponse{},
+	}
+
+	var form *Schema
+	var formFiles bool
+	if rt.binding != nil {
+		for _, fb := range rt.binding.fields {
+			field := rt.args.FieldByIndex(fb.index)
+			if fb.source == formSource {
+				if form == nil {
+					form = &Schema{Type: "object", Properties: map[string]*Schema{}}
+				}
+				form.Properties[fb.name] = formFieldSchema(schemas, field)
+				if hasValidationRule(field, "required") {
+					form.Required = append(form.Required, fb.name)
+				}
+				formFiles = formFiles || isFileType(field.Type)
+				continue
+			}
+			schema := schemas.schemaOf(field.Type)
+			applyValidationRules(schema, field)
+			if fb.source == pathSource {
+				for _, param := range op.Parameters {
+					if param.Name == fb.name && param.Schema.Pattern == "" {
+						param.Schema = schema
+					}
+				}
+				continue
+			}
+			op.Parameters = append(op.Parameters, &OpenAPIParameter{
+				Name:     fb.name,
+				In:       fb.source,
+				Required: hasValidationRule(field, "required"),
+				Schema:   schema,
+			})
+		}
+	}
+
+	if rt.handlerType == customHandlerType && rt.args != nil && hasBodyFields(rt.args) {
+		schema := schemas.schemaOf(rt.args)
+		switch rt.Method {
+		case http.MethodGet, http.MethodDelete, http.MethodHead:
+			op.Parameters = append(op.Parameters, &OpenAPIParameter{
+				Name:    "query",
+				In:      querySource,
+				Content: map[string]*OpenAPIMediaType{jsonMediaType: {schema}},
+			})
+		default:
+			op.RequestBody = &OpenAPIRequestBody{Content: gen.content(schema)}
+		}
+	}
+	if form != nil {
+		if op.RequestBody == nil {
+			op.RequestBody = &OpenAPIRequestBody{Content: map[string]*OpenAPIMediaType{}}
+		}
+		op.RequestBody.Content[MultipartFormMediaType] = &OpenAPIMediaType{form}
+		if !formFiles {
+			op.RequestBody.Content[URLEncodedFormMediaType] = &OpenAPIMediaType{form}
+		}
+	}
+
+	success := &OpenAPIResponse{Description: "Successful response"}
+	if rt.streams() {
+		items := &Schema{}
+		if elem := rt.streamElem(); elem != nil {
+			items = schemas.schemaOf(elem)
+		}
+		schema := &Schema{Type: "array", Items: items}
+		if envelope {
+			schema = &Schema{Type: "object", Properties: map[string]*Schema{"response": schema}}
+		}
+		success.Content = map[string]*OpenAPIMediaType{
+			jsonMediaType:        {schema},
+			NDJSONMediaType:      {items},
+			EventStreamMediaType: {&Schema{Type: "string"}},
+		}
+	} else if rt.servesFile() {
+		success.Content = map[string]*OpenAPIMediaType{
+			"application/octet-stream": {&Schema{Type: "string", Format: "binary"}},
+		}
+	} else if rt.handlerType == customHandlerType {
+		var schema *Schema
+		if reply := rt.reply; reply != nil {
+			if body, ok := responseBodyType(reply); ok {
+				reply = body
+			}
+			schema = schemas.schemaOf(reply)
+		}
+		if envelope {
+			env := &Schema{Type: "object", Properties: map[string]*Schema{}}
+			if schema != nil {
+				env.Properties["response"] = schema
+			}
+			schema = env
+		}
+		if schema != nil {
+			success.Content = gen.content(schema)
+		}
+	}
+	op.Responses["200"] = success
+
+	failure := &OpenAPIResponse{Description: "Error response"}
+	if envelope {
+		failure.Content = gen.content(&Schema{
+			Type:       "object",
+			Properties: map[string]*Schema{"error": schemas.errorSchema()},
+		})
+	}
+	op.Responses["default"] = failure
+	return op
+}
+
+// parsePathTemplate convert mux path template to OpenAPI path and
+// return path parameters. Variable patterns are kept in parameter schema
+func parsePathTemplate(tpl string) (string, []*OpenAPIParameter) {
+	var path strings.Builder
+	var params []*OpenAPIParameter
+	for i := 0; i < len(tpl); i++ {
+		if tpl[i] != '{' {
+			path.WriteByte(tpl[i])
+			continue
+		}
+		depth, end := 0, -1
+		for j := i; j < len(tpl); j++ {
+			if tpl[j] == '{' {
+				depth++
+			} else if tpl[j] == '}' {
+				depth--
+				if depth == 0 {
+					end = j
+					break
+				}
+			}
+		}
+		if end < 0 {
+			path.WriteString(tpl[i:])
+			break
+		}
+		name, pattern := tpl[i+1:end], ""
+		if k := strings.Index(name, ":"); k >= 0 {
+			name, pattern = name[:k], name[k+1:]
+		}
+		schema := &Schema{Type: "string"}
+		if pattern != "" {
+			schema.Pattern = "^" + pattern + "$"
+		}
+		params = append(params, &OpenAPIParameter{
+			Name:     name,
+			In:       pathSource,
+			Required: true,
+			Schema:   schema,
+		})
+		path.WriteString("{" + name + "}")
+		i = end
+	}
+	return path.String(), params
+}
+
+// formFieldSchema return schema of form field, files are binary strings
+func formFieldSchema(schemas *schemaRegistry, field reflect.StructField) *Schema {
+	if !isFileType(field.Type) {
+		schema := schemas.schemaOf(field.Type)
+		applyValidationRules(schema, field)
+		return schema
+	}
+	file := &Schema{Type: "string", Format: "binary"}
+	if field.Type.Kind() == reflect.Slice {
+		return &Schema{Type: "array", Items: file}
+	}
+	return file
+}
+
+// hasBodyFields report whether struct has fields decoded from body
+func hasBodyFields(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return true
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if _, ok := jsonFieldName(t.Field(i)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonFieldName return name of field in JSON. Fields bound from path,
+// query or headers without json tag are skipped
+func jsonFieldName(field reflect.StructField) (string, bool) {
+	if field.PkgPath != "" && !field.Anonymous {
+		return "", false
+	}
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	if name := tagName(tag); name != "" {
+		return name, true
+	}
+	for _, source := range bindingSources {
+		if tagName(field.Tag.Get(source)) != "" {
+			return "", false
+		}
+	}
+	return field.Name, true
+}
+
+func hasValidationRule(field reflect.StructField, name string) bool {
+	for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+		if rule == name || strings.HasPrefix(rule, name+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaRegistry build schemas of Go types. Named structs are placed to
+// components and referenced
+type schemaRegistry struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{
+		schemas: map[string]*Schema{},
+		names:   map[reflect.Type]string{},
+	}
+}
+
+func (sr *schemaRegistry) errorSchema() *Schema {
+	if _, ok := sr.schemas["Error"]; !ok {
+		sr.schemas["FieldError"] = sr.structSchema(reflect.TypeOf(FieldError{}))
+		sr.schemas["Error"] = &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"status":  {Type: "integer"},
+				"code":    {Type: "string"},
+				"msg":     {Type: "string"},
+				"fields":  {Type: "array", Items: &Schema{Ref: "#/components/schemas/FieldError"}},
+				"details": {Type: "object", AdditionalProperties: &Schema{}},
+			},
+			Required: []string{"status", "code", "msg"},
+		}
+	}
+	return &Schema{Ref: "#/components/schemas/Error"}
+}
+
+func (sr *schemaRegistry) schemaOf(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Ptr {
+		s := sr.schemaOf(t.Elem())
+		if s.Ref == "" {
+			s.Nullable = true
+		}
+		return s
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawMessageType:
+		return &Schema{}
+	}
+	if reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: sr.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: sr.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return sr.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + sr.register(t)}
+	}
+	return &Schema{}
+}
+
+// register add named struct to components and return its name
+func (sr *schemaRegistry) register(t reflect.Type) string {
+	if name, ok := sr.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	if _, exists := sr.schemas[name]; exists {
+		name = strings.ReplaceAll(t.String(), ".", "_")
+	}
+	sr.names[t] = name
+	sr.schemas[name] = &Schema{}
+	*sr.schemas[name] = *sr.structSchema(t)
+	return name
+}
+
+func (sr *schemaRegistry) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	sr.addFields(s, t)
+	return s
+}
+
+func (sr *schemaRegistry) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && tagName(field.Tag.Get("json")) == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				sr.addFields(s, ft)
+				continue
+			}
+		}
+		name, ok := jsonFieldName(field)
+		if !ok {
+			continue
+		}
+		fs := sr.schemaOf(field.Type)
+		applyValidationRules(fs, field)
+		s.Properties[name] = fs
+		if hasValidationRule(field, "required") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// applyValidationRules describe validate tag rules in schema
+func applyValidationRules(s *Schema, field reflect.StructField) {
+	tag := field.Tag.Get("validate")
+	if tag == "" || s.Ref != "" {
+		return
+	}
+	rules, _ := parseValidationRules(field, tag)
+	for _, rule := range rules {
+		switch rule.name {
+		case "min", "max":
+			num, n := rule.num, int(rule.num)
+			switch s.Type {
+			case "string":
+				if rule.name == "min" {
+					s.MinLength = &n
+				} else {
+					s.MaxLength = &n
+				}
+			case "array":
+				if rule.name == "min" {
+					s.MinItems = &n
+				} else {
+					s.MaxItems = &n
+				}
+			case "integer", "number":
+				if rule.name == "min" {
+					s.Minimum = &num
+				} else {
+					s.Maximum = &num
+				}
+			}
+		case "email":
+			s.Format = "email"
+		case "oneof":
+			s.Enum = nil
+			for _, item := range rule.list {
+				var value interface{} = item
+				if s.Type == "integer" || s.Type == "number" {
+					if num, err := strconv.ParseFloat(item, 64); err == nil {
+						value = num
+					}
+				}
+				s.Enum = append(s.Enum, value)
+			}
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package restik
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Problem is RFC 7807 problem details object
+type Problem struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Extensions map[string]interface{}
+}
+
+// ProblemDetailer is implemented by errors which contribute problem
+// details fields. ProblemDetails is called with problem filled by defaults
+type ProblemDetailer interface {
+	ProblemDetails(p *Problem)
+}
+
+// NewProblem return problem details of error
+func NewProblem(err error) *Problem {
+	e := FromAnotherError(err)
+	p := &Problem{
+		Type:       "about:blank",
+		Title:      http.StatusText(e.GetStatus()),
+		Status:     e.GetStatus(),
+		Detail:     e.GetMessage(),
+		Extensions: map[string]interface{}{"code": e.GetCode()},
+	}
+	if fe, ok := e.(interface{ GetFields() []FieldError }); ok && len(fe.GetFields()) > 0 {
+		p.Extensions["fields"] = fe.GetFields()
+	}
+	if de, ok := e.(interface{ GetDetails() map[string]interface{} }); ok {
+		for k, v := range de.GetDetails() {
+			p.Extensions[k] = v
+		}
+	}
+	var pd ProblemDetailer
+	if errors.As(e, &pd) {
+		pd.ProblemDetails(p)
+	}
+	return p
+}
+
+// MarshalJSON encode problem with extension members on top level
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		m[k] = v
+	}
+	m["type"] = p.Type
+	m["title"] = p.Title
+	m["status"] = p.Status
+	if p.Detail != "" {
+		m["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		m["instance"] = p.Instance
+	}
+	return json.Marshal(m)
+}
+
+// MarshalXML encode problem in RFC 7807 XML format
+func (p *Problem) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start = xml.StartElement{Name: xml.Name{Space: "urn:ietf:rfc:7807", Local: "problem"}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	members := []struct {
+		name  string
+		value interface{}
+	}{
+		{"type", p.Type},
+		{"title", p.Title},
+		{"status", p.Status},
+		{"detail", p.Detail},
+		{"instance", p.Instance},
+	}
+	for _, m := range members {
+		if s, ok := m.value.(string); ok && s == "" {
+			continue
+		}
+		if err := e.EncodeElement(m.value, xml.StartElement{Name: xml.Name{Local: m.name}}); err != nil {
+			return err
+		}
+	}
+	keys := make([]string, 0, len(p.Extensions))
+	for k := range p.Extensions {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := e.EncodeElement(p.Extensions[k], xml.StartElement{Name: xml.Name{Local: k}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// ProblemReply is Reply which render errors as RFC 7807 problem details.
+// Responses are rendered by wrapped Reply or as is if Reply is nil
+//
+// Using:
+//
+//	r.SetCustomReply(&restik.ProblemReply{})
+type ProblemReply struct {
+	Reply Reply
+
+	response interface{}
+	err      Error
+	// rawErr is original error, it is mapped by router mapper on writing
+	rawErr error
+}
+
+func (pr *ProblemReply) New() Reply {
+	rpl := &ProblemReply{}
+	if pr.Reply != nil {
+		rpl.Reply = pr.Reply.New()
+	}
+	return rpl
+}
+
+func (pr *ProblemReply) SetResponse(resp interface{}) {
+	if pr.Reply != nil {
+		pr.Reply.SetResponse(resp)
+	}
+	pr.response = resp
+}
+
+func (pr *ProblemReply) SetError(err error) {
+	pr.rawErr = err
+	pr.err = FromAnotherError(err)
+}
+
+func (pr *ProblemReply) GetError() error {
+	if pr.rawErr != nil {
+		return pr.rawErr
+	}
+	if pr.err == nil {
+		return nil
+	}
+	return pr.err
+}
+
+// ContentType return problem media type with structured syntax suffix
+// of codec for errors, e.g. application/problem+json
+func (pr *ProblemReply) ContentType(codec Codec) string {
+	if pr.err == nil {
+		return ""
+	}
+	mt := mediaType(codec)
+	if i := strings.Index(mt, "/"); i >= 0 {
+		return mt[:i+1] + "problem+" + mt[i+1:]
+	}
+	return ""
+}
+
+// MarshalJSON encode problem details or response
+func (pr *ProblemReply) MarshalJSON() ([]byte, error) {
+	return json.Marshal(pr.value())
+}
+
+// MarshalXML encode problem details or response
+func (pr *ProblemReply) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if pr.err != nil || pr.Reply != nil {
+		return e.Encode(pr.value())
+	}
+	return e.EncodeElement(pr.response, xml.StartElement{Name: xml.Name{Local: "response"}})
+}
+
+func (pr *ProblemReply) value() interface{} {
+	if pr.err != nil {
+		return NewProblem(pr.err)
+	}
+	if pr.Reply != nil {
+		return pr.Reply
+	}
+	return pr.response
+}
+
+// acceptsProblem report whether Accept header lists problem details
+// media types explicitly
+func acceptsProblem(accept string) bool {
+	for _, mt := range parseAccept(accept) {
+		if strings.HasPrefix(mt, "application/problem+") {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,7 @@
 package restik
 
 import (
+	"bytes"
 	"encoding/json"
 	"net/http"
 )
@@ -10,6 +11,11 @@ type ResponseWriter struct {
 	http.ResponseWriter
 	jsonHeaderSetted bool
 	commonReply      Reply
+	codec            Codec
+	defaultCodec     Codec
+	notAcceptable    bool
+	errorMapper      *ErrorMapper
+	status           int
 }
 
 // NewResponseWriter create new ResponseWriter instance
@@ -39,7 +45,7 @@ func (w *ResponseWriter) WriteResponse(resp interface{}) (int, error) {
 		return 0, nil
 	}
 	rpl := w.commonReply.New()
-	rpl.SetResponse(resp)
+	rpl.SetResponse(w.setResponseMeta(resp))
 	return w.WriteReply(rpl)
 }
 
@@ -47,26 +53,82 @@ func (w *ResponseWriter) WriteError(err error) bool {
 	if err == nil {
 		return false
 	}
-	selfErr := FromAnotherError(err)
+	selfErr := w.mapError(err)
 	rpl := w.commonReply.New()
 	rpl.SetError(selfErr)
 	w.WriteReply(rpl)
 	return true
 }
 
-func (w *ResponseWriter) WriteReply(rpl Reply) (int, error) {
-	b, err := json.Marshal(rpl)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
-		return 0, err
+// mapError convert err to Error by mapper of router
+func (w *ResponseWriter) mapError(err error) Error {
+	if w.errorMapper == nil {
+		return FromAnotherError(err)
+	}
+	return w.errorMapper.MapError(err)
+}
+
+// Codec return codec negotiated by Accept header of request
+func (w *ResponseWriter) Codec() Codec {
+	if w.codec == nil {
+		return JSONCodec
 	}
-	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	return w.codec
+}
+
+// WriteReply encode reply by negotiated codec and write it with status
+// of reply error or status set by handler result
+func (w *ResponseWriter) WriteReply(rpl Reply) (int, error) {
+	status := http.StatusOK
 	if err := rpl.GetError(); err != nil {
-		err := FromAnotherError(err)
-		w.WriteHeader(err.GetStatus())
-	} else {
-		w.WriteHeader(http.StatusOK)
+		// error is mapped once, so status and body of reply agree
+		mapped := w.mapError(err)
+		status = mapped.GetStatus()
+		rpl.SetError(mapped)
+	} else if w.status != 0 {
+		status = w.status
+	}
+	if !bodyAllowed(status) {
+		w.WriteHeader(status)
+		return 0, nil
 	}
+
+	codec := w.Codec()
+	b, contentType, err := encodeReply(codec, rpl)
+	if err != nil {
+		// reply can not be encoded, e.g. map by XML codec, so internal
+		// error is answered by default codec
+		encodeErr := err
+		errRpl := w.commonReply.New()
+		errRpl.SetError(newStatusError(http.StatusInternalServerError, "encode_failed", "Failed to encode response").WithCause(encodeErr))
+		if b, contentType, err = encodeReply(w.getDefaultCodec(), errRpl); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return 0, encodeErr
+		}
+		status = http.StatusInternalServerError
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
 	return w.Write(b)
 }
+
+// getDefaultCodec return default codec of router
+func (w *ResponseWriter) getDefaultCodec() Codec {
+	if w.defaultCodec == nil {
+		return JSONCodec
+	}
+	return w.defaultCodec
+}
+
+// encodeReply encode reply by codec and return it with content type
+func encodeReply(codec Codec, rpl Reply) ([]byte, string, error) {
+	var buf bytes.Buffer
+	if err := codec.NewEncoder(&buf).Encode(rpl); err != nil {
+		return nil, "", err
+	}
+	contentType := codec.ContentType()
+	if ct, ok := rpl.(interface{ ContentType(Codec) string }); ok && ct.ContentType(codec) != "" {
+		contentType = ct.ContentType(codec)
+	}
+	return buf.Bytes(), contentType, nil
+}
@@ -0,0 +1,132 @@
+package restik
+
+import (
+	"net/http"
+	"reflect"
+)
+
+// StatusResponse is implemented by handler results which set status
+// of successful response. Status outside of 100-599 range is ignored,
+// so domain types with other Status method are written with 200 status
+type StatusResponse interface {
+	Status() int
+}
+
+// HeadersResponse is implemented by handler results which add headers
+// to successful response
+type HeadersResponse interface {
+	Headers() http.Header
+}
+
+// bodyResponse is implemented by results wrapping reply body
+type bodyResponse interface {
+	responseBody() interface{}
+}
+
+// Response is handler result with status and headers of response.
+// Body is written to reply, responses with 204 and 304 statuses have
+// no body
+//
+// Using:
+//
+//	func createUser(arg *createUserArg) (restik.Response[*User], error) {
+//		u, err := store.Create(arg)
+//		if err != nil {
+//			return restik.Response[*User]{}, err
+//		}
+//		return restik.Created("/users/"+u.ID, u), nil
+//	}
+type Response[T any] struct {
+	StatusCode int
+	Header     http.Header
+	Body       T
+}
+
+// Created return response with 201 status and Location header
+func Created[T any](location string, body T) Response[T] {
+	return Response[T]{
+		StatusCode: http.StatusCreated,
+		Header:     http.Header{"Location": {location}},
+		Body:       body,
+	}
+}
+
+// Accepted return response with 202 status
+func Accepted[T any](body T) Response[T] {
+	return Response[T]{StatusCode: http.StatusAccepted, Body: body}
+}
+
+// NoContent return response with 204 status and without body
+func NoContent() Response[struct{}] {
+	return Response[struct{}]{StatusCode: http.StatusNoContent}
+}
+
+// Status return status of response, 200 if it is not set
+func (r Response[T]) Status() int {
+	if r.StatusCode == 0 {
+		return http.StatusOK
+	}
+	return r.StatusCode
+}
+
+// Headers return headers of response
+func (r Response[T]) Headers() http.Header {
+	return r.Header
+}
+
+// WithHeader return copy of response with header added
+func (r Response[T]) WithHeader(key, value string) Response[T] {
+	h := r.Header.Clone()
+	if h == nil {
+		h = http.Header{}
+	}
+	h.Add(key, value)
+	r.Header = h
+	return r
+}
+
+func (r Response[T]) responseBody() interface{} {
+	return r.Body
+}
+
+var bodyResponseType = reflect.TypeOf((*bodyResponse)(nil)).Elem()
+
+// responseBodyType return type of body if t is Response
+func responseBodyType(t reflect.Type) (reflect.Type, bool) {
+	if !t.Implements(bodyResponseType) || t.Kind() != reflect.Struct {
+		return nil, false
+	}
+	field, ok := t.FieldByName("Body")
+	if !ok {
+		return nil, false
+	}
+	return field.Type, true
+}
+
+// setResponseMeta apply status and headers of handler result and
+// return body of reply
+func (w *ResponseWriter) setResponseMeta(resp interface{}) interface{} {
+	if s, ok := resp.(StatusResponse); ok {
+		if status := s.Status(); status >= 100 && status <= 599 {
+			w.status = status
+		}
+	}
+	if h, ok := resp.(HeadersResponse); ok {
+		header := w.Header()
+		for k, vs := range h.Headers() {
+			for _, v := range vs {
+				header.Add(k, v)
+			}
+		}
+	}
+	if b, ok := resp.(bodyResponse); ok {
+		return b.responseBody()
+	}
+	return resp
+}
+
+// bodyAllowed report whether response with status can have body
+func bodyAllowed(status int) bool {
+	return status != http.StatusNoContent && status != http.StatusNotModified &&
+		(status < 100 || status >= 200)
+}
@@ -0,0 +1,61 @@
+package restik
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// order is domain type with Status method not meaning HTTP status
+type order struct {
+	ID    string `json:"id"`
+	State int    `json:"state"`
+}
+
+func (o order) Status() int {
+	return o.State
+}
+
+func TestResponseStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler interface{}
+		status  int
+		body    string
+		header  string
+	}{
+		{"created", func() (Response[string], error) {
+			return Created("/orders/1", "ok"), nil
+		}, http.StatusCreated, `{"response":"ok"}`, "/orders/1"},
+		{"no content", func() (Response[struct{}], error) {
+			return NoContent(), nil
+		}, http.StatusNoContent, "", ""},
+		{"response error status", func() (Response[string], error) {
+			return Response[string]{StatusCode: http.StatusConflict, Body: "dup"}, nil
+		}, http.StatusConflict, `{"response":"dup"}`, ""},
+		{"domain status out of range", func() (order, error) {
+			return order{ID: "1", State: 3}, nil
+		}, http.StatusOK, `{"response":{"id":"1","state":3}}`, ""},
+		{"domain status too large", func() (*order, error) {
+			return &order{ID: "2", State: 1000}, nil
+		}, http.StatusOK, `{"response":{"id":"2","state":1000}}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRouter()
+			r.Get("/orders", tt.handler)
+			w := httptest.NewRecorder()
+			r.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/orders", nil))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if body := strings.TrimSpace(w.Body.String()); body != tt.body {
+				t.Errorf("body = %s, want %s", body, tt.body)
+			}
+			if loc := w.Header().Get("Location"); loc != tt.header {
+				t.Errorf("Location = %q, want %q", loc, tt.header)
+			}
+		})
+	}
+}
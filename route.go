@@ -1,12 +1,14 @@
 package restik
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"net/url"
 	"reflect"
+
+	"github.com/gorilla/mux"
 )
 
 type routeHandlerType int
@@ -15,6 +17,7 @@ const (
 	customHandlerType routeHandlerType = iota
 	httpHandlerType
 	restHandlerType
+	webSocketHandlerType
 )
 
 type (
@@ -22,6 +25,20 @@ type (
 	restHandler func(ResponseWriter, *Request)
 )
 
+type inputKind int
+
+const (
+	contextInput inputKind = iota
+	requestInput
+	argsInput
+)
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	requestType = reflect.TypeOf((*Request)(nil))
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
 type routes map[string]*Route
 
 func (rts routes) find(method, endpoint string) (*Route, bool) {
@@ -35,32 +52,69 @@ type Route struct {
 	Method   string
 	Endpoint string
 
+	name        string
+	router      *Router
+	muxRoute    *mux.Route
+	group       *Group
+	middlewares middlewareList
+	chain       chainCache
+
+	summary     string
+	description string
+	tags        []string
+
+	maxMemory     int64
+	maxUploadSize int64
+	decodeOptions *DecodeOptions
+
+	// implicit is set for routes added by router, e.g. OPTIONS routes
+	implicit bool
+
 	handlerType routeHandlerType
 	httpHandler httpHandler
 	restHandler restHandler
 
+	webSocketHandler WebSocketHandler
+	checkOrigin      func(*Request) bool
+
+	inputs      []inputKind
 	args        reflect.Type
 	argsIsPtr   bool
+	binding     *binding
+	validator   *validator
 	reply       reflect.Type
-	inputCount  int
 	outputCount int
 	fn          reflect.Value
+	typed       func(*Request) (interface{}, error)
 }
 
 // NewRoute create new route by http method and endpoint.
 // fn can be one of
 //
-//	func([*Request | [,] *struct]) [*struct | [,] error]
+//	func([context.Context] [,] [*Request] [,] [*struct]) [*struct | [,] error]
 //	func(http.ResponseWriter, *http.Request)
 //	func(rest.ResponseWriter, *rest.Request)
+//	func(*rest.WebSocketConn, *rest.Request)
+//
+// NewRoute panics if fn has unsupported signature
 func NewRoute(method, endpoint string, fn interface{}) *Route {
+	rt, err := NewRouteE(method, endpoint, fn)
+	if err != nil {
+		panic(err)
+	}
+	return rt
+}
+
+// NewRouteE create new route like NewRoute, but return error
+// if fn has unsupported signature
+func NewRouteE(method, endpoint string, fn interface{}) (*Route, error) {
 	if httpHndl, ok := fn.(func(http.ResponseWriter, *http.Request)); ok {
 		return &Route{
 			Method:      method,
 			Endpoint:    endpoint,
 			handlerType: httpHandlerType,
 			httpHandler: httpHndl,
-		}
+		}, nil
 	}
 
 	if restHndl, ok := fn.(func(ResponseWriter, *Request)); ok {
@@ -69,52 +123,166 @@ func NewRoute(method, endpoint string, fn interface{}) *Route {
 			Endpoint:    endpoint,
 			handlerType: restHandlerType,
 			restHandler: restHndl,
-		}
+		}, nil
+	}
+
+	if wsHndl, ok := webSocketHandlerOf(fn); ok {
+		return &Route{
+			Method:           method,
+			Endpoint:         endpoint,
+			handlerType:      webSocketHandlerType,
+			webSocketHandler: wsHndl,
+		}, nil
 	}
 
 	fnTypeOf := reflect.TypeOf(fn)
-	args, argsIsPtr := parseInput(fnTypeOf)
+	if err := checkSignature(fnTypeOf); err != nil {
+		return nil, fmt.Errorf("restik: invalid handler %v for %s %s: %w", fnTypeOf, method, endpoint, err)
+	}
+	inputs, args, argsIsPtr := parseInput(fnTypeOf)
+	validator, err := newValidator(args)
+	if err != nil {
+		return nil, fmt.Errorf("restik: invalid argument %v for %s %s: %w", args, method, endpoint, err)
+	}
 	return &Route{
 		Method:      method,
 		Endpoint:    endpoint,
-		inputCount:  fnTypeOf.NumIn(),
+		inputs:      inputs,
 		outputCount: fnTypeOf.NumOut(),
 		args:        args,
 		argsIsPtr:   argsIsPtr,
+		binding:     newBinding(args),
+		validator:   validator,
 		reply:       parseOutput(fnTypeOf),
 		fn:          reflect.ValueOf(fn),
-	}
+	}, nil
 }
 
-func parseInput(fnType reflect.Type) (reflect.Type, bool) {
-	cnt := fnType.NumIn()
-	if cnt == 0 {
-		return nil, false
+// checkSignature return error if fnType is not supported handler type
+func checkSignature(fnType reflect.Type) error {
+	if fnType == nil || fnType.Kind() != reflect.Func {
+		return errors.New("handler must be a function")
+	}
+	if fnType.IsVariadic() {
+		return errors.New("variadic functions are not supported")
+	}
+
+	if fnType.NumIn() > 3 {
+		return fmt.Errorf("arguments count must be 3 or less, got %d", fnType.NumIn())
+	}
+	last := inputKind(-1)
+	for i := 0; i < fnType.NumIn(); i++ {
+		in := fnType.In(i)
+		kind := argsInput
+		switch in {
+		case contextType:
+			kind = contextInput
+		case requestType:
+			kind = requestInput
+		}
+		if kind <= last {
+			return fmt.Errorf("argument %d of type %v is out of order, "+
+				"arguments must be [context.Context] [*restik.Request] [arg] each at most once", i+1, in)
+		}
+		if kind == argsInput {
+			if err := checkArgsType(in); err != nil {
+				return fmt.Errorf("argument %d: %w", i+1, err)
+			}
+		}
+		last = kind
+	}
+
+	switch fnType.NumOut() {
+	case 0, 1:
+	case 2:
+		if fnType.Out(1) != errorType {
+			return fmt.Errorf("second result must be error, got %v", fnType.Out(1))
+		}
+		if fnType.Out(0) == errorType {
+			return errors.New("first result of two must not be error")
+		}
+	default:
+		return fmt.Errorf("results count must be 2 or less, got %d", fnType.NumOut())
 	}
-	if cnt > 2 {
-		panic("Function arguments count must be 2 or less")
+	if fnType.NumOut() > 0 && fnType.Out(0) != errorType {
+		if err := checkReplyType(fnType.Out(0)); err != nil {
+			return fmt.Errorf("first result: %w", err)
+		}
 	}
-	elem, isPtr := fnType.In(cnt-1), false
+	return nil
+}
+
+func checkArgsType(t reflect.Type) error {
+	elem := t
 	if elem.Kind() == reflect.Ptr {
-		isPtr = true
 		elem = elem.Elem()
 	}
-	if elem.String() != "restik.Request" {
-		return elem, isPtr
+	switch {
+	case elem == requestType.Elem():
+		return errors.New("restik.Request must be passed by pointer")
+	case elem == contextType || elem.Kind() == reflect.Ptr:
+		return fmt.Errorf("unsupported type %v", t)
+	}
+	switch elem.Kind() {
+	case reflect.Func, reflect.Chan, reflect.Interface, reflect.UnsafePointer, reflect.Complex64, reflect.Complex128:
+		return fmt.Errorf("unsupported type %v", t)
 	}
-	return nil, false
+	return nil
+}
+
+func checkReplyType(t reflect.Type) error {
+	switch t.Kind() {
+	case reflect.Chan:
+		// channels are streamed
+		if t.ChanDir()&reflect.RecvDir == 0 {
+			return fmt.Errorf("unsupported send-only channel %v", t)
+		}
+	case reflect.Func:
+		// iterators and stream functions are streamed
+		if !isSeqType(t) && !t.Implements(streamType) {
+			return fmt.Errorf("unsupported type %v", t)
+		}
+	case reflect.UnsafePointer, reflect.Complex64, reflect.Complex128:
+		return fmt.Errorf("unsupported type %v", t)
+	}
+	return nil
+}
+
+// encodesReply report whether handler result is encoded by codec.
+// Streams and files are written as is
+func (rt *Route) encodesReply() bool {
+	return !rt.streams() && !rt.servesFile()
+}
+
+func parseInput(fnType reflect.Type) ([]inputKind, reflect.Type, bool) {
+	var inputs []inputKind
+	var args reflect.Type
+	var isPtr bool
+	for i := 0; i < fnType.NumIn(); i++ {
+		in := fnType.In(i)
+		switch in {
+		case contextType:
+			inputs = append(inputs, contextInput)
+		case requestType:
+			inputs = append(inputs, requestInput)
+		default:
+			inputs = append(inputs, argsInput)
+			args = in
+			if args.Kind() == reflect.Ptr {
+				isPtr = true
+				args = args.Elem()
+			}
+		}
+	}
+	return inputs, args, isPtr
 }
 
 func parseOutput(fnType reflect.Type) reflect.Type {
-	cnt := fnType.NumOut()
-	if cnt == 0 {
+	if fnType.NumOut() == 0 {
 		return nil
 	}
-	if cnt > 2 {
-		panic("Function arguments count must be 2 or less")
-	}
 	elem := fnType.Out(0)
-	if elem.String() == "error" {
+	if elem == errorType {
 		return nil
 	}
 	if elem.Kind() == reflect.Ptr {
@@ -123,18 +291,56 @@ func parseOutput(fnType reflect.Type) reflect.Type {
 	return elem
 }
 
-func (rt Route) getKey() string {
+// Use add middlewares to route. Route middlewares run after router
+// and group middlewares
+func (rt *Route) Use(middlewares ...Middleware) *Route {
+	rt.middlewares.add(middlewares...)
+	rt.chain.reset()
+	return rt
+}
+
+// UseFunc add middleware functions to route
+func (rt *Route) UseFunc(middlewareFuncs ...func(HandlerFunc) HandlerFunc) *Route {
+	rt.middlewares.add(funcMiddlewares(middlewareFuncs)...)
+	rt.chain.reset()
+	return rt
+}
+
+// wrap wrap handler with route and group middlewares
+func (rt *Route) wrap(handle HandlerFunc) HandlerFunc {
+	middlewares := rt.middlewares.get()
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handle = middlewares[i].Middleware(handle)
+	}
+	if rt.group != nil {
+		handle = rt.group.wrap(handle)
+	}
+	return handle
+}
+
+// handler return compiled chain of route and group middlewares around
+// router handler of route
+func (rt *Route) handler() HandlerFunc {
+	return rt.chain.get(rt.router.generation(), func() HandlerFunc {
+		return rt.wrap(rt.router.execRoute)
+	})
+}
+
+func (rt *Route) getKey() string {
 	return fmt.Sprintf("%s:%s", rt.Method, rt.Endpoint)
 }
 
-func (rt Route) exec(rr *Request, rpl Reply) {
+// call bind arguments and call handler function
+func (rt *Route) call(rr *Request) (interface{}, error) {
+	if rt.typed != nil {
+		return rt.typed(rr)
+	}
+
 	fnArgs, err := rt.getArgs(rr)
 	if err != nil {
-		rpl.SetError(err)
-		return
+		return nil, err
 	}
-	var resValue []reflect.Value
-	resValue = rt.fn.Call(fnArgs)
+	resValue := rt.fn.Call(fnArgs)
 
 	var replyVal interface{}
 	var errVal interface{}
@@ -150,14 +356,8 @@ func (rt Route) exec(rr *Request, rpl Reply) {
 		errVal = resValue[1].Interface()
 	}
 
-	if errVal != nil {
-		err, _ := errVal.(error)
-		rpl.SetError(err)
-	}
-	if replyVal != nil {
-		rpl.SetResponse(replyVal)
-	}
-	return
+	err, _ = errVal.(error)
+	return replyVal, err
 }
 
 func (rt *Route) getArgs(rr *Request) ([]reflect.Value, error) {
@@ -166,36 +366,85 @@ func (rt *Route) getArgs(rr *Request) ([]reflect.Value, error) {
 
 	var argsVal reflect.Value
 	if rt.args != nil {
-		argsVal = reflect.New(rt.args)
-		queries := rr.URL.Query()
-		query := queries.Get("query")
-		var queryRaw []byte
-		if query != "" {
-			unQuery, _ := url.QueryUnescape(query)
-			queryRaw = []byte(unQuery)
-		} else if rr.ContentLength > 0 {
-			queryRaw, _ = ioutil.ReadAll(rr.Body)
-		}
-		if queryRaw != nil && len(queryRaw) > 0 {
-			err := json.Unmarshal(queryRaw, argsVal.Interface())
-			if err != nil {
-				return nil, NewBadRequestError()
-			}
+		var err error
+		argsVal, err = rt.bindArgs(rr)
+		if err != nil {
+			return nil, err
 		}
+	}
 
-		if !rt.argsIsPtr {
-			argsVal = argsVal.Elem()
+	for _, input := range rt.inputs {
+		switch input {
+		case contextInput:
+			fnArgs = append(fnArgs, reflect.ValueOf(rr.Context()))
+		case requestInput:
+			fnArgs = append(fnArgs, req)
+		case argsInput:
+			fnArgs = append(fnArgs, argsVal)
 		}
 	}
+	return fnArgs, nil
+}
 
-	if rt.inputCount == 1 {
-		if rt.args != nil {
-			fnArgs = []reflect.Value{argsVal}
-		} else {
-			fnArgs = []reflect.Value{req}
+// bindArgs decode, bind and validate handler argument from request.
+// Returned value has type of handler argument
+func (rt *Route) bindArgs(rr *Request) (reflect.Value, error) {
+	argsVal := reflect.New(rt.args)
+	isForm := false
+	if rt.binding != nil && rt.binding.form {
+		var err error
+		if isForm, err = rt.parseForm(rr); err != nil {
+			return reflect.Value{}, err
 		}
-	} else if rt.inputCount == 2 {
-		fnArgs = []reflect.Value{req, argsVal}
 	}
-	return fnArgs, nil
+	opts := rt.getDecodeOptions()
+	queries := rr.URL.Query()
+	query := queries.Get("query")
+	var queryRaw []byte
+	codec := JSONCodec
+	if query != "" {
+		unQuery, _ := url.QueryUnescape(query)
+		queryRaw = []byte(unQuery)
+	} else if !isForm && rr.ContentLength != 0 && rr.Body != nil && rr.Body != http.NoBody {
+		var err error
+		if queryRaw, err = readBody(rr, opts); err != nil {
+			return reflect.Value{}, err
+		}
+		if len(queryRaw) > 0 {
+			contentType := rr.Header.Get("Content-Type")
+			if contentType == "" && opts.RequireContentType {
+				return reflect.Value{}, NewUnsupportedMediaTypeError("content_type_required", "Content-Type header is required")
+			}
+			if rt.router != nil {
+				var ok bool
+				codec, ok = rt.router.codecs.forContentType(contentType)
+				if !ok {
+					// clients like curl send JSON as urlencoded form,
+					// so unknown types are decoded by default codec
+					if opts.RequireContentType {
+						return reflect.Value{}, NewUnsupportedMediaTypeError()
+					}
+					codec = rt.router.codecs[0]
+				}
+			}
+		}
+	}
+	if len(queryRaw) > 0 {
+		if err := decode(codec, queryRaw, argsVal.Interface(), opts); err != nil {
+			return reflect.Value{}, err
+		}
+	}
+	if rt.binding != nil {
+		if err := rt.binding.bind(rr, argsVal.Elem()); err != nil {
+			return reflect.Value{}, err
+		}
+	}
+	if err := validateArgs(rt.validator, argsVal); err != nil {
+		return reflect.Value{}, err
+	}
+
+	if !rt.argsIsPtr {
+		argsVal = argsVal.Elem()
+	}
+	return argsVal, nil
 }
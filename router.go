@@ -2,27 +2,42 @@ package restik
 
 import (
 	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
 
 	"github.com/gorilla/mux"
 )
 
 // Router is main router in rest
 type Router struct {
+	// gen is generation of middlewares, it is changed on every Use call.
+	// It is first field for 64-bit alignment of atomic operations
+	gen uint64
+
 	routes                  routes
+	names                   map[string]*Route
 	muxRouter               *mux.Router
-	middlewares             []Middleware
+	middlewares             middlewareList
+	chain                   chainCache
 	notFoundHandler         func(ResponseWriter, *Request)
 	methodNotAllowedHandler func(ResponseWriter, *Request)
 	replyImpl               Reply
+	codecs                  codecs
+	errorMapper             *ErrorMapper
+	decodeOptions           DecodeOptions
+	openAPIInfo             OpenAPIInfo
 }
 
 // NewRouter create new Router
 func NewRouter() *Router {
 	r := &Router{
 		routes:      routes{},
+		names:       map[string]*Route{},
 		muxRouter:   mux.NewRouter(),
-		middlewares: make([]Middleware, 0),
 		replyImpl:   &serveReply{},
+		codecs:      codecs{JSONCodec},
+		errorMapper: NewErrorMapper(),
 	}
 	r.muxRouter.Handle("/", r)
 	r.muxRouter.MethodNotAllowedHandler = methodNotAllowedHandler{r}
@@ -38,12 +53,99 @@ func (r *Router) Handler() *mux.Router {
 // Add add new routers
 func (r *Router) Add(rts ...*Route) *Router {
 	for _, rt := range rts {
-		r.routes[rt.getKey()] = rt
-		r.muxRouter.Handle(rt.Endpoint, r).Methods(rt.Method)
+		if rt.name != "" {
+			r.checkName(rt.name, rt)
+		}
+		rt.router = r
+		key := rt.getKey()
+		if old, ok := r.routes[key]; ok && old.implicit {
+			rt.muxRoute = old.muxRoute
+		} else {
+			methods := []string{rt.Method}
+			if rt.Method == http.MethodGet {
+				// HEAD requests are answered by GET handler without body
+				methods = append(methods, http.MethodHead)
+			}
+			rt.muxRoute = r.muxRouter.Handle(rt.Endpoint, r).Methods(methods...)
+		}
+		r.routes[key] = rt
+		if rt.name != "" {
+			r.addName(rt)
+		}
+		if rt.Method != http.MethodOptions {
+			r.addOptionsRoute(rt)
+		}
 	}
 	return r
 }
 
+// addOptionsRoute add implicit OPTIONS route for endpoint of rt, so
+// preflight requests run through middlewares instead of 405 handler
+func (r *Router) addOptionsRoute(rt *Route) {
+	if _, ok := r.routes.find(http.MethodOptions, rt.Endpoint); ok {
+		return
+	}
+	opt := &Route{
+		Method:      http.MethodOptions,
+		Endpoint:    rt.Endpoint,
+		router:      r,
+		group:       rt.group,
+		handlerType: restHandlerType,
+		restHandler: r.answerOptions,
+		implicit:    true,
+	}
+	r.routes[opt.getKey()] = opt
+	opt.muxRoute = r.muxRouter.Handle(rt.Endpoint, r).Methods(http.MethodOptions)
+}
+
+// answerOptions answer OPTIONS requests which are not handled by
+// middlewares with methods allowed for request URL
+func (r *Router) answerOptions(rw ResponseWriter, rr *Request) {
+	rw.Header().Set("Allow", strings.Join(r.allowedMethods(rr.Request), ", "))
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+// allowedMethods return sorted methods of routes matching request URL
+func (r *Router) allowedMethods(hr *http.Request) []string {
+	candidates := map[string]bool{}
+	for _, rt := range r.routes {
+		candidates[rt.Method] = true
+		if rt.Method == http.MethodGet {
+			candidates[http.MethodHead] = true
+		}
+	}
+	var methods []string
+	for method := range candidates {
+		req := *hr
+		req.Method = method
+		var match mux.RouteMatch
+		if !r.muxRouter.Match(&req, &match) || match.MatchErr != nil {
+			continue
+		}
+		pathTemplate, err := match.Route.GetPathTemplate()
+		if err != nil {
+			continue
+		}
+		if _, ok := r.findRoute(method, pathTemplate); ok {
+			methods = append(methods, method)
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// Handle add new route with method to router and return.
+// Unlike Get, Post and others it return error instead of panic
+// if fn has unsupported signature
+func (r *Router) Handle(method, endpoint string, fn interface{}) (*Route, error) {
+	rt, err := NewRouteE(method, endpoint, fn)
+	if err != nil {
+		return nil, err
+	}
+	r.Add(rt)
+	return rt, nil
+}
+
 // Get add new route with GET method to router and return
 func (r *Router) Get(endpoint string, fn interface{}) *Route {
 	rt := NewRoute("GET", endpoint, fn)
@@ -90,24 +192,43 @@ func (r *Router) SetMethodNotAllowedHandlerFunc(handler func(ResponseWriter, *Re
 }
 
 func (r *Router) Use(middlewares ...Middleware) *Router {
-	r.middlewares = append(r.middlewares, middlewares...)
+	r.middlewares.add(middlewares...)
+	r.invalidate()
 	return r
 }
 
 func (r *Router) UseFunc(middlewareFuncs ...func(HandlerFunc) HandlerFunc) *Router {
-	for _, f := range middlewareFuncs {
-		r.middlewares = append(r.middlewares, &funcMiddleware{f})
-	}
+	r.middlewares.add(funcMiddlewares(middlewareFuncs)...)
+	r.invalidate()
 	return r
 }
 
 func (r *Router) ServeHTTP(hw http.ResponseWriter, hr *http.Request) {
+	handle := r.chain.get(r.generation(), r.buildChain)
+	rt, _ := r.getCurrentRoute(hr)
+	if rt != nil && hr.Method == http.MethodHead && rt.Method == http.MethodGet {
+		hw = headResponseWriter{hw}
+	}
+	handle(r.newResponseWriter(hw, hr), NewRequest(hr, rt))
+}
+
+// buildChain wrap route handler with router middlewares
+func (r *Router) buildChain() HandlerFunc {
 	handle := r.routeHandler
-	for i := len(r.middlewares) - 1; i >= 0; i-- {
-		handle = r.middlewares[i].Middleware(handle)
+	middlewares := r.middlewares.get()
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handle = middlewares[i].Middleware(handle)
 	}
-	rt, _ := r.getCurrentRoute(hr)
-	handle(NewResponseWriter(hw, r.replyImpl), NewRequest(hr, rt))
+	return handle
+}
+
+// invalidate drop compiled middlewares chains of router, groups and routes
+func (r *Router) invalidate() {
+	atomic.AddUint64(&r.gen, 1)
+}
+
+func (r *Router) generation() uint64 {
+	return atomic.LoadUint64(&r.gen)
 }
 
 func (r *Router) routeHandler(rw ResponseWriter, rr *Request) {
@@ -121,6 +242,11 @@ func (r *Router) routeHandler(rw ResponseWriter, rr *Request) {
 		return
 	}
 
+	rt.handler()(rw, rr)
+}
+
+func (r *Router) execRoute(rw ResponseWriter, rr *Request) {
+	rt := rr.Route
 	if rt.handlerType == httpHandlerType {
 		rt.httpHandler(rw.ResponseWriter, rr.Request)
 		return
@@ -131,8 +257,36 @@ func (r *Router) routeHandler(rw ResponseWriter, rr *Request) {
 		return
 	}
 
-	rpl := r.replyImpl.New()
-	rt.exec(rr, rpl)
+	if rt.handlerType == webSocketHandlerType {
+		rt.serveWebSocket(rw, rr)
+		return
+	}
+
+	if rw.notAcceptable && rt.encodesReply() {
+		rw.WriteError(NewNotAcceptableError())
+		return
+	}
+
+	defer removeMultipartForm(rr)
+	rpl := rw.commonReply.New()
+	resp, err := rt.call(rr)
+	if err == nil {
+		resp = rw.setResponseMeta(resp)
+		if writeStream(rw, rr, resp) || writeFile(rw, rr, resp) {
+			return
+		}
+	} else if b, ok := resp.(bodyResponse); ok {
+		resp = b.responseBody()
+	}
+	if !rt.encodesReply() {
+		resp = nil
+	}
+	if err != nil {
+		rpl.SetError(rw.mapError(err))
+	}
+	if resp != nil {
+		rpl.SetResponse(resp)
+	}
 	rw.WriteReply(rpl)
 }
 
@@ -145,7 +299,17 @@ func (r *Router) getCurrentRoute(hr *http.Request) (*Route, bool) {
 	if err != nil {
 		return nil, false
 	}
-	return r.routes.find(hr.Method, pathTemplate)
+	return r.findRoute(hr.Method, pathTemplate)
+}
+
+// findRoute return route by method and path template. GET route is
+// returned for HEAD method if there is no HEAD route
+func (r *Router) findRoute(method, pathTemplate string) (*Route, bool) {
+	rt, ok := r.routes.find(method, pathTemplate)
+	if !ok && method == http.MethodHead {
+		return r.routes.find(http.MethodGet, pathTemplate)
+	}
+	return rt, ok
 }
 
 func (r *Router) SetCustomReply(rpl Reply) {
@@ -157,11 +321,11 @@ type notFoundHandler struct {
 }
 
 func (h notFoundHandler) ServeHTTP(hw http.ResponseWriter, hr *http.Request) {
+	rw := h.r.newResponseWriter(hw, hr)
 	if h.r.notFoundHandler != nil {
-		h.r.notFoundHandler(NewResponseWriter(hw, h.r.replyImpl), NewRequest(hr, nil))
+		h.r.notFoundHandler(rw, NewRequest(hr, nil))
 		return
 	}
-	rw := NewResponseWriter(hw, h.r.replyImpl)
 	rw.WriteError(NewNotFoundError())
 }
 
@@ -170,10 +334,26 @@ type methodNotAllowedHandler struct {
 }
 
 func (h methodNotAllowedHandler) ServeHTTP(hw http.ResponseWriter, hr *http.Request) {
+	rw := h.r.newResponseWriter(hw, hr)
+	rw.Header().Set("Allow", strings.Join(h.r.allowedMethods(hr), ", "))
 	if h.r.methodNotAllowedHandler != nil {
-		h.r.methodNotAllowedHandler(NewResponseWriter(hw, h.r.replyImpl), NewRequest(hr, nil))
+		h.r.methodNotAllowedHandler(rw, NewRequest(hr, nil))
 		return
 	}
-	rw := NewResponseWriter(hw, h.r.replyImpl)
-	rw.WriteError(NewError(http.StatusMethodNotAllowed, "not_allowed", "Method not allowed"))
+	rw.WriteError(NewMethodNotAllowedError())
+}
+
+// headResponseWriter discard body of GET handler answering HEAD request
+type headResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w headResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (w headResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
 }
@@ -0,0 +1,73 @@
+package restik
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHeadAndOptions(t *testing.T) {
+	head := func(w ResponseWriter, r *Request) {
+		w.Header().Set("X-Head", "explicit")
+		w.WriteHeader(http.StatusNoContent)
+	}
+	routers := map[string]*Router{"get first": NewRouter(), "get last": NewRouter()}
+	r := routers["get first"]
+	r.Get("/users/{id}", helloHandler)
+	r.Delete("/users/me", helloHandler)
+	r.Post("/users", helloHandler)
+	r.Get("/ping", helloHandler)
+	r.Handle("HEAD", "/ping", head)
+	r = routers["get last"]
+	r.Delete("/users/me", helloHandler)
+	r.Get("/users/{id}", helloHandler)
+	r.Post("/users", helloHandler)
+	r.Handle("HEAD", "/ping", head)
+	r.Get("/ping", helloHandler)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+		allow  string
+		body   bool
+	}{
+		{"get", "GET", "/users/1", http.StatusOK, "", true},
+		{"head without body", "HEAD", "/users/1", http.StatusOK, "", false},
+		{"options", "OPTIONS", "/users/1", http.StatusNoContent, "GET, HEAD, OPTIONS", false},
+		{"overlapping get", "GET", "/users/me", http.StatusOK, "", true},
+		{"overlapping delete", "DELETE", "/users/me", http.StatusOK, "", true},
+		{"overlapping options", "OPTIONS", "/users/me", http.StatusNoContent, "DELETE, GET, HEAD, OPTIONS", false},
+		{"not allowed", "PUT", "/users/1", http.StatusMethodNotAllowed, "GET, HEAD, OPTIONS", true},
+		{"overlapping not allowed", "PUT", "/users/me", http.StatusMethodNotAllowed, "DELETE, GET, HEAD, OPTIONS", true},
+		{"delete not allowed", "DELETE", "/users/1", http.StatusMethodNotAllowed, "GET, HEAD, OPTIONS", true},
+		{"head not allowed", "HEAD", "/users", http.StatusMethodNotAllowed, "OPTIONS, POST", true},
+		{"explicit head", "HEAD", "/ping", http.StatusNoContent, "", false},
+		{"get next to explicit head", "GET", "/ping", http.StatusOK, "", true},
+		{"options of explicit head", "OPTIONS", "/ping", http.StatusNoContent, "GET, HEAD, OPTIONS", false},
+	}
+	for name, r := range routers {
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				w := httptest.NewRecorder()
+				r.Handler().ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+				if w.Code != tt.status {
+					t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body)
+				}
+				if allow := w.Header().Get("Allow"); allow != tt.allow {
+					t.Errorf("Allow = %q, want %q", allow, tt.allow)
+				}
+				if hasBody := w.Body.Len() > 0; hasBody != tt.body {
+					t.Errorf("body = %q, want body %v", w.Body, tt.body)
+				}
+			})
+		}
+
+		w := httptest.NewRecorder()
+		r.Handler().ServeHTTP(w, httptest.NewRequest("HEAD", "/ping", nil))
+		if h := w.Header().Get("X-Head"); h != "explicit" {
+			t.Errorf("%s: HEAD /ping is not answered by HEAD route, X-Head = %q", name, h)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package restik
 
+import "encoding/xml"
+
 type Reply interface {
 	New() Reply
 	SetResponse(interface{})
@@ -8,8 +10,12 @@ type Reply interface {
 }
 
 type serveReply struct {
-	Response interface{} `json:"response,omitempty"`
-	Error    Error       `json:"error,omitempty"`
+	XMLName  xml.Name    `json:"-" xml:"reply"`
+	Response interface{} `json:"response,omitempty" xml:"response,omitempty"`
+	Error    Error       `json:"error,omitempty" xml:"error,omitempty"`
+
+	// err is original error, it is mapped by router mapper on writing
+	err error
 }
 
 func (sr *serveReply) New() Reply {
@@ -21,9 +27,13 @@ func (sr *serveReply) SetResponse(resp interface{}) {
 }
 
 func (sr *serveReply) SetError(err error) {
+	sr.err = err
 	sr.Error = FromAnotherError(err)
 }
 
 func (sr *serveReply) GetError() error {
+	if sr.err != nil {
+		return sr.err
+	}
 	return sr.Error
 }
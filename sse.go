@@ -0,0 +1,236 @@
+package restik
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultHeartbeatInterval is interval of heartbeat comments in event
+// streams of handlers returning channels
+const DefaultHeartbeatInterval = 15 * time.Second
+
+// Event is server-sent event. Data of string and []byte types is sent
+// as is, other values are encoded to JSON
+type Event struct {
+	ID    string
+	Event string
+	Retry time.Duration
+	Data  interface{}
+}
+
+var errStreamClosed = errors.New("restik: event stream is closed")
+
+// EventStream write server-sent events (text/event-stream) to client.
+// It is used by handlers returning channels, and can be used directly
+// in rest handlers
+//
+// Using:
+//
+//	func events(w restik.ResponseWriter, r *restik.Request) {
+//		stream, err := restik.NewEventStream(w, r)
+//		if err != nil {
+//			w.WriteError(err)
+//			return
+//		}
+//		defer stream.Close()
+//		stream.Heartbeat(restik.DefaultHeartbeatInterval)
+//		for {
+//			select {
+//			case <-stream.Done():
+//				return
+//			case ev := <-source:
+//				stream.Send(restik.Event{Event: "update", Data: ev})
+//			}
+//		}
+//	}
+type EventStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	ctx     context.Context
+	mu      sync.Mutex
+	stop    chan struct{}
+	once    sync.Once
+}
+
+// NewEventStream write event stream headers and return stream.
+// It return error if response writer does not support flushing
+func NewEventStream(w ResponseWriter, r *Request) (*EventStream, error) {
+	flusher, ok := w.ResponseWriter.(http.Flusher)
+	if !ok {
+		return nil, NewInternalError("streaming_unsupported", "Streaming is not supported")
+	}
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	return &EventStream{
+		w:       w.ResponseWriter,
+		flusher: flusher,
+		ctx:     r.Context(),
+		stop:    make(chan struct{}),
+	}, nil
+}
+
+// Send write event and flush it to client
+func (s *EventStream) Send(e Event) error {
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if e.ID != "" {
+		fmt.Fprintf(&buf, "id: %s\n", oneLine(e.ID))
+	}
+	if e.Event != "" {
+		fmt.Fprintf(&buf, "event: %s\n", oneLine(e.Event))
+	}
+	if e.Retry > 0 {
+		fmt.Fprintf(&buf, "retry: %s\n", strconv.FormatInt(e.Retry.Milliseconds(), 10))
+	}
+	data, err := eventData(e.Data)
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&buf, "data: %s\n", line)
+	}
+	buf.WriteByte('\n')
+	return s.write(buf.Bytes())
+}
+
+// SendData write event with data only
+func (s *EventStream) SendData(data interface{}) error {
+	return s.Send(Event{Data: data})
+}
+
+// Heartbeat start sending comments every interval to keep connection
+// alive until stream is closed or request is canceled
+func (s *EventStream) Heartbeat(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if s.ping() != nil {
+					return
+				}
+			case <-s.stop:
+				return
+			case <-s.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Done return channel closed when request is canceled
+func (s *EventStream) Done() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+// Close stop heartbeat. Stream must be closed before handler returns
+func (s *EventStream) Close() {
+	s.once.Do(func() {
+		close(s.stop)
+	})
+	s.mu.Lock()
+	s.w = nil
+	s.mu.Unlock()
+}
+
+func (s *EventStream) ping() error {
+	return s.write([]byte(": ping\n\n"))
+}
+
+func (s *EventStream) write(b []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.w == nil {
+		return errStreamClosed
+	}
+	if _, err := s.w.Write(b); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// serveChannel send values received from channel ch as events until
+// channel is closed or request is canceled
+func (s *EventStream) serveChannel(ch reflect.Value, heartbeat time.Duration) error {
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: ch},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(s.ctx.Done())},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ticker.C)},
+	}
+	for {
+		chosen, v, ok := reflect.Select(cases)
+		var err error
+		switch chosen {
+		case 0:
+			if !ok {
+				return nil
+			}
+			if e, ok := v.Interface().(Event); ok {
+				err = s.Send(e)
+			} else {
+				err = s.SendData(v.Interface())
+			}
+		case 1:
+			return s.ctx.Err()
+		case 2:
+			err = s.ping()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// writeEvents answer with event stream of values from channel ch
+func writeEvents(rw ResponseWriter, rr *Request, ch reflect.Value) {
+	stream, err := NewEventStream(rw, rr)
+	if err != nil {
+		rw.WriteError(err)
+		return
+	}
+	defer stream.Close()
+	if !ch.IsValid() || ch.IsNil() {
+		return
+	}
+	stream.serveChannel(ch, DefaultHeartbeatInterval)
+}
+
+func eventData(data interface{}) (string, error) {
+	switch d := data.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return d, nil
+	case []byte:
+		return string(d), nil
+	}
+	b, err := json.Marshal(data)
+	return string(b), err
+}
+
+// oneLine remove line breaks from event field
+func oneLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
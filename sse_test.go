@@ -0,0 +1,44 @@
+package restik
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEventStreamChannelOfInterfaces(t *testing.T) {
+	r := NewRouter()
+	r.Get("/events", func() (<-chan interface{}, error) {
+		ch := make(chan interface{}, 2)
+		ch <- Event{ID: "1", Event: "tick", Data: "a"}
+		ch <- map[string]int{"n": 2}
+		close(ch)
+		return ch, nil
+	})
+
+	req := httptest.NewRequest("GET", "/events", nil)
+	req.Header.Set("Accept", EventStreamMediaType)
+	w := httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, req)
+	want := "id: 1\nevent: tick\ndata: a\n\ndata: {\"n\":2}\n\n"
+	if w.Body.String() != want {
+		t.Errorf("body = %q, want %q", w.Body, want)
+	}
+}
+
+func TestEventStreamSendAfterClose(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/events", nil)
+	rw := NewResponseWriter(rec, &serveReply{})
+	s, err := NewEventStream(rw, NewRequest(req, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SendData("x"); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	if err := s.SendData("y"); !errors.Is(err, errStreamClosed) {
+		t.Errorf("Send after Close = %v, want errStreamClosed", err)
+	}
+}
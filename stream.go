@@ -0,0 +1,382 @@
+package restik
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"reflect"
+	"time"
+)
+
+// Media types of streamed responses
+const (
+	NDJSONMediaType      = "application/x-ndjson"
+	EventStreamMediaType = "text/event-stream"
+)
+
+const (
+	streamChunkSize     = 32 << 10
+	streamFlushInterval = 200 * time.Millisecond
+)
+
+// Stream is sequence of values written to response incrementally.
+// Each call yield for every value until sequence ends, yield return error
+// or ctx is canceled.
+//
+// Handlers returning Stream, channel or iterator func(yield func(T) bool)
+// answer with JSON array, in reply envelope for default reply. Values are sent
+// as newline delimited JSON if client accept application/x-ndjson and as
+// server-sent events if client accept text/event-stream
+type Stream interface {
+	Each(ctx context.Context, yield func(v interface{}) error) error
+}
+
+// StreamFunc is function implementing Stream
+//
+// Using:
+//
+//	func export(ctx context.Context) (restik.Stream, error) {
+//		rows, err := db.QueryContext(ctx, "SELECT id, name FROM users")
+//		if err != nil {
+//			return nil, err
+//		}
+//		return restik.StreamFunc(func(ctx context.Context, yield func(interface{}) error) error {
+//			defer rows.Close()
+//			for rows.Next() {
+//				var u User
+//				if err := rows.Scan(&u.ID, &u.Name); err != nil {
+//					return err
+//				}
+//				if err := yield(u); err != nil {
+//					return err
+//				}
+//			}
+//			return rows.Err()
+//		}), nil
+//	}
+type StreamFunc func(ctx context.Context, yield func(v interface{}) error) error
+
+// Each call f
+func (f StreamFunc) Each(ctx context.Context, yield func(v interface{}) error) error {
+	return f(ctx, yield)
+}
+
+// StreamOf return stream of values received from channel ch until it
+// is closed
+func StreamOf[T any](ch <-chan T) Stream {
+	return StreamFunc(func(ctx context.Context, yield func(interface{}) error) error {
+		for {
+			select {
+			case v, ok := <-ch:
+				if !ok {
+					return nil
+				}
+				if err := yield(v); err != nil {
+					return err
+				}
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	})
+}
+
+// StreamSeq return stream of values of iterator seq
+func StreamSeq[T any](seq func(yield func(T) bool)) Stream {
+	return StreamFunc(func(ctx context.Context, yield func(interface{}) error) error {
+		var err error
+		seq(func(v T) bool {
+			if err = ctx.Err(); err != nil {
+				return false
+			}
+			err = yield(v)
+			return err == nil
+		})
+		return err
+	})
+}
+
+var streamType = reflect.TypeOf((*Stream)(nil)).Elem()
+
+// isSeqType report whether t is iterator func(yield func(T) bool)
+func isSeqType(t reflect.Type) bool {
+	if t.Kind() != reflect.Func || t.NumIn() != 1 || t.NumOut() != 0 {
+		return false
+	}
+	yield := t.In(0)
+	return yield.Kind() == reflect.Func && yield.NumIn() == 1 && yield.NumOut() == 1 &&
+		yield.Out(0).Kind() == reflect.Bool && !yield.IsVariadic()
+}
+
+// streams report whether route handler return channel, iterator
+// or Stream which are written to response incrementally
+func (rt *Route) streams() bool {
+	if rt.reply == nil {
+		return false
+	}
+	return rt.reply.Kind() == reflect.Chan || isSeqType(rt.reply) ||
+		rt.reply.Implements(streamType) || reflect.PtrTo(rt.reply).Implements(streamType)
+}
+
+// streamElem return type of streamed values if it is known
+func (rt *Route) streamElem() reflect.Type {
+	switch {
+	case rt.reply.Kind() == reflect.Chan:
+		return rt.reply.Elem()
+	case isSeqType(rt.reply):
+		return rt.reply.In(0).In(0)
+	}
+	return nil
+}
+
+// seqStream return stream of values of reflected iterator
+func seqStream(seq reflect.Value) Stream {
+	yieldType := seq.Type().In(0)
+	return StreamFunc(func(ctx context.Context, yield func(interface{}) error) error {
+		var err error
+		seq.Call([]reflect.Value{reflect.MakeFunc(yieldType, func(args []reflect.Value) []reflect.Value {
+			if err = ctx.Err(); err == nil {
+				err = yield(args[0].Interface())
+			}
+			return []reflect.Value{reflect.ValueOf(err == nil)}
+		})})
+		return err
+	})
+}
+
+// chanStream return stream of values of reflected channel
+func chanStream(ch reflect.Value) Stream {
+	return StreamFunc(func(ctx context.Context, yield func(interface{}) error) error {
+		cases := []reflect.SelectCase{
+			{Dir: reflect.SelectRecv, Chan: ch},
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+		}
+		for {
+			chosen, v, ok := reflect.Select(cases)
+			if chosen == 1 {
+				return ctx.Err()
+			}
+			if !ok {
+				return nil
+			}
+			if err := yield(v.Interface()); err != nil {
+				return err
+			}
+		}
+	})
+}
+
+// writeStream write streamed result of handler and report whether
+// resp is streamed. Stream format is negotiated by Accept header the
+// same way for channels, iterators and streams
+func writeStream(rw ResponseWriter, rr *Request, resp interface{}) bool {
+	v := reflect.ValueOf(resp)
+	if !v.IsValid() {
+		return false
+	}
+	mt := streamMediaType(rr.Header.Get("Accept"))
+	var s Stream
+	switch {
+	case v.Kind() == reflect.Chan:
+		if mt == EventStreamMediaType {
+			writeEvents(rw, rr, v)
+			return true
+		}
+		if v.IsNil() {
+			s = StreamFunc(func(context.Context, func(interface{}) error) error { return nil })
+		} else {
+			s = chanStream(v)
+		}
+	case isSeqType(v.Type()):
+		if v.IsNil() {
+			return false
+		}
+		s = seqStream(v)
+	default:
+		var ok bool
+		if s, ok = resp.(Stream); !ok {
+			return false
+		}
+	}
+
+	codec := JSONCodec
+	if rr.Route != nil && rr.Route.router != nil {
+		if c, ok := rr.Route.router.codecs.find("application/json"); ok {
+			codec = c
+		}
+	}
+	_, envelope := rw.commonReply.(*serveReply)
+	sw := &streamWriter{rw: rw, codec: codec, envelope: envelope}
+	switch mt {
+	case NDJSONMediaType:
+		sw.writeLines(rr.Context(), s)
+	case EventStreamMediaType:
+		writeStreamEvents(rw, rr, s)
+	default:
+		sw.writeArray(rr.Context(), s)
+	}
+	return true
+}
+
+// streamMediaType return media type of streamed response preferred
+// by client
+func streamMediaType(accept string) string {
+	for _, mt := range parseAccept(accept) {
+		switch mt {
+		case NDJSONMediaType, EventStreamMediaType:
+			return mt
+		case "application/json", "application/*", "*/*":
+			return "application/json"
+		}
+	}
+	return "application/json"
+}
+
+// writeStreamEvents write values of stream as server-sent events.
+// Error of stream is sent as event of "error" type
+func writeStreamEvents(rw ResponseWriter, rr *Request, s Stream) {
+	es, err := NewEventStream(rw, rr)
+	if err != nil {
+		rw.WriteError(err)
+		return
+	}
+	defer es.Close()
+	es.Heartbeat(DefaultHeartbeatInterval)
+	err = s.Each(rr.Context(), func(v interface{}) error {
+		if e, ok := v.(Event); ok {
+			return es.Send(e)
+		}
+		return es.SendData(v)
+	})
+	if err != nil && rr.Context().Err() == nil {
+		es.Send(Event{Event: "error", Data: rw.mapError(err)})
+	}
+}
+
+// streamWriter write encoded values to response in chunks. Headers
+// are written with first value, so errors of stream before it are
+// answered with error reply
+type streamWriter struct {
+	rw       ResponseWriter
+	codec    Codec
+	envelope bool
+
+	contentType string
+	started     bool
+	buf         bytes.Buffer
+	flushed     time.Time
+}
+
+// writeArray write stream as JSON array. Error after first value is
+// written to "error" member of envelope, without envelope response
+// is truncated
+func (sw *streamWriter) writeArray(ctx context.Context, s Stream) {
+	sw.contentType = sw.codec.ContentType()
+	open, end := "[", "]"
+	if sw.envelope {
+		open, end = `{"response":[`, "]}"
+	}
+	sep := open
+	err := s.Each(ctx, func(v interface{}) error {
+		b, err := sw.encode(v)
+		if err != nil {
+			return err
+		}
+		sw.write([]byte(sep))
+		sep = ","
+		return sw.write(b)
+	})
+	if err != nil && !sw.started {
+		sw.rw.WriteError(err)
+		return
+	}
+	if sep == open {
+		sw.write([]byte(open))
+	}
+	if err != nil {
+		if !sw.envelope || ctx.Err() != nil {
+			sw.flush()
+			return
+		}
+		b, encErr := sw.encode(sw.rw.mapError(err))
+		if encErr != nil {
+			sw.flush()
+			return
+		}
+		sw.write([]byte(`],"error":`))
+		sw.write(b)
+		end = "}"
+	}
+	sw.write([]byte(end))
+	sw.flush()
+}
+
+// writeLines write stream as newline delimited JSON. Error after first
+// value is written as line with "error" member for default reply
+func (sw *streamWriter) writeLines(ctx context.Context, s Stream) {
+	sw.contentType = NDJSONMediaType
+	err := s.Each(ctx, func(v interface{}) error {
+		b, err := sw.encode(v)
+		if err != nil {
+			return err
+		}
+		return sw.write(append(b, '\n'))
+	})
+	if err != nil && !sw.started {
+		sw.rw.WriteError(err)
+		return
+	}
+	if err != nil && sw.envelope && ctx.Err() == nil {
+		if b, encErr := sw.encode(struct {
+			Error Error `json:"error"`
+		}{sw.rw.mapError(err)}); encErr == nil {
+			sw.write(append(b, '\n'))
+		}
+	}
+	sw.start()
+	sw.flush()
+}
+
+func (sw *streamWriter) encode(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := sw.codec.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+func (sw *streamWriter) start() {
+	if sw.started {
+		return
+	}
+	sw.started = true
+	sw.flushed = time.Now()
+	sw.rw.Header().Set("Content-Type", sw.contentType)
+	sw.rw.WriteHeader(http.StatusOK)
+}
+
+// write buffer b and flush buffer when it is large or was flushed
+// long ago
+func (sw *streamWriter) write(b []byte) error {
+	sw.start()
+	sw.buf.Write(b)
+	if sw.buf.Len() >= streamChunkSize || time.Since(sw.flushed) >= streamFlushInterval {
+		return sw.flush()
+	}
+	return nil
+}
+
+func (sw *streamWriter) flush() error {
+	sw.flushed = time.Now()
+	if sw.buf.Len() > 0 {
+		_, err := sw.rw.Write(sw.buf.Bytes())
+		sw.buf.Reset()
+		if err != nil {
+			return err
+		}
+	}
+	if f, ok := sw.rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package restik
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestChannelStreamFormats(t *testing.T) {
+	r := NewRouter()
+	r.Get("/numbers", func() (<-chan int, error) {
+		ch := make(chan int, 3)
+		ch <- 1
+		ch <- 2
+		ch <- 3
+		close(ch)
+		return ch, nil
+	})
+
+	tests := []struct {
+		accept      string
+		contentType string
+		body        string
+	}{
+		{"", "application/json", `{"response":[1,2,3]}`},
+		{"application/json", "application/json", `{"response":[1,2,3]}`},
+		{"*/*", "application/json", `{"response":[1,2,3]}`},
+		{NDJSONMediaType, NDJSONMediaType, "1\n2\n3\n"},
+		{EventStreamMediaType, EventStreamMediaType, "data: 1\n\ndata: 2\n\ndata: 3\n\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/numbers", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			r.Handler().ServeHTTP(w, req)
+			if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, tt.contentType) {
+				t.Errorf("Content-Type = %q, want %s", ct, tt.contentType)
+			}
+			if body := strings.TrimSpace(w.Body.String()); body != strings.TrimSpace(tt.body) {
+				t.Errorf("body = %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
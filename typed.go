@@ -0,0 +1,70 @@
+package restik
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// Handle add new route with typed handler to router and return.
+// Argument is decoded, bound and validated like for reflective handlers,
+// but handler is called directly without reflection
+//
+// Using:
+//
+//	restik.Handle(r, "GET", "/users/{id}", func(ctx context.Context, req *restik.Request, arg getUserArg) (*User, error) {
+//		...
+//	})
+func Handle[In, Out any](r *Router, method, endpoint string, fn func(context.Context, *Request, In) (Out, error)) *Route {
+	rt := NewTypedRoute(method, endpoint, fn)
+	r.Add(rt)
+	return rt
+}
+
+// NewTypedRoute create new route with typed handler. It can be added
+// to router or group by Add method. NewTypedRoute panics if In is not
+// supported argument type
+func NewTypedRoute[In, Out any](method, endpoint string, fn func(context.Context, *Request, In) (Out, error)) *Route {
+	inType := reflect.TypeOf((*In)(nil)).Elem()
+	outType := reflect.TypeOf((*Out)(nil)).Elem()
+	if err := checkArgsType(inType); err != nil {
+		panic(fmt.Errorf("restik: invalid handler argument for %s %s: %w", method, endpoint, err))
+	}
+	if err := checkReplyType(outType); err != nil {
+		panic(fmt.Errorf("restik: invalid handler result for %s %s: %w", method, endpoint, err))
+	}
+
+	args, argsIsPtr := inType, false
+	if args.Kind() == reflect.Ptr {
+		args, argsIsPtr = args.Elem(), true
+	}
+	reply := outType
+	if reply.Kind() == reflect.Ptr {
+		reply = reply.Elem()
+	}
+	validator, err := newValidator(args)
+	if err != nil {
+		panic(fmt.Errorf("restik: invalid argument %v for %s %s: %w", args, method, endpoint, err))
+	}
+
+	rt := &Route{
+		Method:      method,
+		Endpoint:    endpoint,
+		inputs:      []inputKind{contextInput, requestInput, argsInput},
+		outputCount: 2,
+		args:        args,
+		argsIsPtr:   argsIsPtr,
+		binding:     newBinding(args),
+		validator:   validator,
+		reply:       reply,
+	}
+	rt.typed = func(rr *Request) (interface{}, error) {
+		argsVal, err := rt.bindArgs(rr)
+		if err != nil {
+			return nil, err
+		}
+		out, err := fn(rr.Context(), rr, argsVal.Interface().(In))
+		return out, err
+	}
+	return rt
+}
@@ -0,0 +1,107 @@
+package restik
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Name set name of route for building its URL by Router.URL and
+// Request.URLFor. Name is also used as operationId in OpenAPI document.
+// Name panics if router already has other route with the same name.
+// Empty name remove name of route
+func (rt *Route) Name(name string) *Route {
+	if rt.router == nil {
+		rt.name = name
+		return rt
+	}
+	if name != "" {
+		rt.router.checkName(name, rt)
+	}
+	if rt.name != "" {
+		delete(rt.router.names, rt.name)
+	}
+	rt.name = name
+	if name != "" {
+		rt.router.addName(rt)
+	}
+	return rt
+}
+
+func (r *Router) addName(rt *Route) {
+	r.checkName(rt.name, rt)
+	r.names[rt.name] = rt
+}
+
+// checkName panics if name is used by other route than rt
+func (r *Router) checkName(name string, rt *Route) {
+	if other, ok := r.names[name]; ok && other != rt {
+		panic(fmt.Errorf("restik: duplicate route name %q for %s %s", name, rt.Method, rt.Endpoint))
+	}
+}
+
+// URL build path of named route. Pairs are names and values of path
+// variables, values must match patterns of variables. Other pairs are
+// added to query string
+//
+// Using:
+//
+//	r.Get("/users/{id:[0-9]+}", getUser).Name("user")
+//	u, err := r.URL("user", "id", "42", "fields", "name") // /users/42?fields=name
+func (r *Router) URL(name string, pairs ...string) (*url.URL, error) {
+	rt, ok := r.names[name]
+	if !ok {
+		return nil, fmt.Errorf("restik: route %q not found", name)
+	}
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("restik: odd number of URL parameters for route %q", name)
+	}
+	_, params := parsePathTemplate(rt.Endpoint)
+	vars := make(map[string]bool, len(params))
+	for _, p := range params {
+		vars[p.Name] = true
+	}
+	var pathPairs []string
+	query := url.Values{}
+	for i := 0; i < len(pairs); i += 2 {
+		if vars[pairs[i]] {
+			pathPairs = append(pathPairs, pairs[i], pairs[i+1])
+		} else {
+			query.Add(pairs[i], pairs[i+1])
+		}
+	}
+	u, err := rt.muxRoute.URLPath(pathPairs...)
+	if err != nil {
+		return nil, fmt.Errorf("restik: route %q: %w", name, err)
+	}
+	u.RawQuery = query.Encode()
+	return u, nil
+}
+
+// URLFor build absolute URL of named route with scheme and host of
+// request. Scheme is taken from X-Forwarded-Proto header if it is set
+// by proxy
+func (r *Request) URLFor(name string, pairs ...string) (*url.URL, error) {
+	if r.Route == nil || r.Route.router == nil {
+		return nil, errors.New("restik: request is not routed")
+	}
+	u, err := r.Route.router.URL(name, pairs...)
+	if err != nil {
+		return nil, err
+	}
+	u.Scheme = requestScheme(r.Request)
+	u.Host = r.Host
+	return u, nil
+}
+
+func requestScheme(hr *http.Request) string {
+	if proto := strings.ToLower(strings.TrimSpace(strings.Split(hr.Header.Get("X-Forwarded-Proto"), ",")[0])); proto == "http" || proto == "https" {
+		return proto
+	}
+	if hr.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
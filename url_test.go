@@ -0,0 +1,133 @@
+package restik
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRouterURL(t *testing.T) {
+	r := NewRouter()
+	r.Get("/users/{id:[0-9]+}", helloHandler).Name("user")
+	r.Group("/api").Get("/posts/{slug}/comments/{n:[0-9]+}", helloHandler).Name("comment")
+	r.Get("/ping", helloHandler).Name("ping")
+
+	tests := []struct {
+		name  string
+		route string
+		pairs []string
+		url   string
+		err   string
+	}{
+		{"path variable", "user", []string{"id", "42"}, "/users/42", ""},
+		{"query pairs", "user", []string{"id", "42", "fields", "name", "fields", "email"}, "/users/42?fields=name&fields=email", ""},
+		{"escaped query", "ping", []string{"q", "a b&c"}, "/ping?q=a+b%26c", ""},
+		{"group prefix", "comment", []string{"slug", "hello", "n", "3"}, "/api/posts/hello/comments/3", ""},
+		{"pattern mismatch", "user", []string{"id", "abc"}, "", "doesn't match"},
+		{"missing variable", "comment", []string{"slug", "hello"}, "", `"comment"`},
+		{"odd pairs", "user", []string{"id"}, "", "odd number"},
+		{"unknown route", "users", nil, "", "not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := r.URL(tt.route, tt.pairs...)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want %s", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if u.String() != tt.url {
+				t.Errorf("url = %s, want %s", u, tt.url)
+			}
+		})
+	}
+}
+
+func TestRouteRename(t *testing.T) {
+	r := NewRouter()
+	rt := r.Get("/users/{id}", helloHandler).Name("user")
+	rt.Name("profile")
+	if _, err := r.URL("user", "id", "1"); err == nil {
+		t.Error("old name is still registered")
+	}
+	if u, err := r.URL("profile", "id", "1"); err != nil || u.Path != "/users/1" {
+		t.Errorf("url = %v, err = %v", u, err)
+	}
+	// name of route added later is registered by Add
+	r.Add(NewRoute("GET", "/posts", helloHandler).Name("posts"))
+	if _, err := r.URL("posts"); err != nil {
+		t.Error(err)
+	}
+	rt.Name("")
+	if _, ok := r.names[""]; ok || len(r.names) != 1 {
+		t.Errorf("names = %v", r.names)
+	}
+}
+
+func TestRouteDuplicateName(t *testing.T) {
+	r := NewRouter()
+	users := r.Get("/users", helloHandler).Name("users")
+	posts := r.Get("/posts", helloHandler).Name("posts")
+
+	mustPanic := func(name string, fn func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%s: expected panic", name)
+			}
+		}()
+		fn()
+	}
+	mustPanic("Name", func() { posts.Name("users") })
+	mustPanic("Add", func() { r.Add(NewRoute("GET", "/other", helloHandler).Name("users")) })
+
+	// failed rename keep previous names and route is not added
+	if r.names["posts"] != posts || r.names["users"] != users {
+		t.Errorf("names = %v", r.names)
+	}
+	if _, ok := r.routes.find("GET", "/other"); ok {
+		t.Error("route with duplicate name is added")
+	}
+	// same name again is allowed
+	users.Name("users")
+}
+
+func TestRequestURLFor(t *testing.T) {
+	r := NewRouter()
+	r.Get("/users/{id}", helloHandler).Name("user")
+	r.Get("/link", func(w ResponseWriter, rr *Request) {
+		u, err := rr.URLFor("user", "id", "7")
+		if err != nil {
+			w.WriteError(err)
+			return
+		}
+		w.Write([]byte(u.String()))
+	})
+
+	tests := []struct {
+		proto string
+		url   string
+	}{
+		{"", "http://example.com/users/7"},
+		{"https", "https://example.com/users/7"},
+		{"HTTPS, http", "https://example.com/users/7"},
+		{"ftp", "http://example.com/users/7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.proto, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/link", nil)
+			if tt.proto != "" {
+				req.Header.Set("X-Forwarded-Proto", tt.proto)
+			}
+			w := httptest.NewRecorder()
+			r.Handler().ServeHTTP(w, req)
+			if w.Body.String() != tt.url {
+				t.Errorf("url = %s, want %s", w.Body, tt.url)
+			}
+		})
+	}
+}
@@ -0,0 +1,288 @@
+package restik
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Validator is implemented by argument types with custom validation.
+// Validate is called after validation by tags
+type Validator interface {
+	Validate() error
+}
+
+var validatorType = reflect.TypeOf((*Validator)(nil)).Elem()
+
+// validator check argument struct fields by validate tags. It is built
+// once on route creation
+//
+// Supported rules:
+//
+//	`validate:"required"`
+//	`validate:"min=3,max=64"`
+//	`validate:"email"`
+//	`validate:"oneof=a b c"`
+//	`validate:"omitempty,min=1"`
+//
+// Rules except required are not checked for empty values, so optional
+// fields are validated only when they are set. Omitempty make it
+// explicit. Fields of nested structs and pointers to structs are
+// validated too, nil pointers are skipped. Self-referencing types are
+// validated at first level only
+type validator struct {
+	fields []fieldValidation
+	// visiting is set of struct types being collected, it stop
+	// recursion of self-referencing types
+	visiting map[reflect.Type]bool
+}
+
+type fieldValidation struct {
+	index []int
+	name  string
+	rules []validationRule
+}
+
+type validationRule struct {
+	name  string
+	param string
+	num   float64
+	list  []string
+}
+
+// newValidator return validator for struct type t or nil if t has not
+// fields with validate tags. It return error on unknown or malformed rules
+func newValidator(t reflect.Type) (*validator, error) {
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, nil
+	}
+	v := &validator{visiting: map[reflect.Type]bool{}}
+	if err := v.collect(t, nil, ""); err != nil {
+		return nil, err
+	}
+	v.visiting = nil
+	if len(v.fields) == 0 {
+		return nil, nil
+	}
+	return v, nil
+}
+
+func (v *validator) collect(t reflect.Type, index []int, prefix string) error {
+	if v.visiting[t] {
+		return nil
+	}
+	v.visiting[t] = true
+	defer delete(v.visiting, t)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldIndex := append(append([]int{}, index...), i)
+		structType := field.Type
+		if structType.Kind() == reflect.Ptr {
+			structType = structType.Elem()
+		}
+		isStruct := structType.Kind() == reflect.Struct && structType != timeType
+		if field.Anonymous && isStruct {
+			if err := v.collect(structType, fieldIndex, prefix); err != nil {
+				return err
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		name := prefix + fieldName(field)
+		if tag := field.Tag.Get("validate"); tag != "" && tag != "-" {
+			rules, err := parseValidationRules(field, tag)
+			if err != nil {
+				return err
+			}
+			v.fields = append(v.fields, fieldValidation{
+				index: fieldIndex,
+				name:  name,
+				rules: rules,
+			})
+		}
+		if isStruct {
+			if err := v.collect(structType, fieldIndex, name+"."); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// fieldName return name of field as client see it
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", pathSource, querySource, headerSource, formSource} {
+		if name := tagName(field.Tag.Get(tag)); name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+func parseValidationRules(field reflect.StructField, tag string) ([]validationRule, error) {
+	var rules []validationRule
+	for _, s := range strings.Split(tag, ",") {
+		rule := validationRule{name: s}
+		if i := strings.Index(s, "="); i >= 0 {
+			rule.name, rule.param = s[:i], s[i+1:]
+		}
+		switch rule.name {
+		case "required", "email", "omitempty":
+		case "min", "max":
+			num, err := strconv.ParseFloat(rule.param, 64)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: rule %q must have numeric parameter", field.Name, rule.name)
+			}
+			rule.num = num
+		case "oneof":
+			rule.list = strings.Fields(rule.param)
+		default:
+			return nil, fmt.Errorf("field %s: unknown validation rule %q", field.Name, rule.name)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// validate return violations of struct value val
+func (v *validator) validate(val reflect.Value) []FieldError {
+	var violations []FieldError
+	for _, fv := range v.fields {
+		field, ok := fieldByIndex(val, fv.index)
+		if !ok {
+			continue
+		}
+		for _, rule := range fv.rules {
+			if msg := rule.check(field); msg != "" {
+				violations = append(violations, FieldError{fv.name, rule.name, msg})
+				break
+			}
+		}
+	}
+	return violations
+}
+
+// fieldByIndex return nested field of val. It return false if field
+// is in struct of nil pointer
+func fieldByIndex(val reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && val.Kind() == reflect.Ptr {
+			if val.IsNil() {
+				return reflect.Value{}, false
+			}
+			val = val.Elem()
+		}
+		val = val.Field(x)
+	}
+	return val, true
+}
+
+// check return violation message or empty string. Empty values are
+// checked only by required rule, set pointers are checked by their values
+func (rule validationRule) check(field reflect.Value) string {
+	if rule.name == "required" {
+		if isEmptyValue(field) {
+			return "is required"
+		}
+		return ""
+	}
+	if isEmptyValue(field) {
+		return ""
+	}
+
+	for field.Kind() == reflect.Ptr || field.Kind() == reflect.Interface {
+		if field.IsNil() {
+			return ""
+		}
+		field = field.Elem()
+	}
+
+	switch rule.name {
+	case "min", "max":
+		n, isLen, ok := measure(field)
+		if !ok {
+			return ""
+		}
+		if rule.name == "min" && n < rule.num {
+			if isLen {
+				return fmt.Sprintf("must have length at least %s", rule.param)
+			}
+			return fmt.Sprintf("must be at least %s", rule.param)
+		}
+		if rule.name == "max" && n > rule.num {
+			if isLen {
+				return fmt.Sprintf("must have length at most %s", rule.param)
+			}
+			return fmt.Sprintf("must be at most %s", rule.param)
+		}
+	case "email":
+		if field.Kind() != reflect.String {
+			return ""
+		}
+		addr, err := mail.ParseAddress(field.String())
+		if err != nil || addr.Address != field.String() {
+			return "must be a valid email address"
+		}
+	case "oneof":
+		s := fmt.Sprint(field.Interface())
+		for _, item := range rule.list {
+			if s == item {
+				return ""
+			}
+		}
+		return fmt.Sprintf("must be one of: %s", strings.Join(rule.list, ", "))
+	}
+	return ""
+}
+
+// measure return length of strings and collections or numeric value.
+// It return false for unsupported kinds
+func measure(field reflect.Value) (n float64, isLen bool, ok bool) {
+	switch field.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(field.String())), true, true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(field.Len()), true, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(field.Int()), false, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(field.Uint()), false, true
+	case reflect.Float32, reflect.Float64:
+		return field.Float(), false, true
+	}
+	return 0, false, false
+}
+
+func isEmptyValue(field reflect.Value) bool {
+	switch field.Kind() {
+	case reflect.Slice, reflect.Map:
+		return field.Len() == 0
+	}
+	return field.IsZero()
+}
+
+// validateArgs validate argument by tags and Validator interface.
+// argsVal must be pointer to argument
+func validateArgs(v *validator, argsVal reflect.Value) error {
+	if v != nil {
+		if violations := v.validate(argsVal.Elem()); len(violations) > 0 {
+			return NewValidationError(violations...)
+		}
+	}
+	if !argsVal.Type().Implements(validatorType) {
+		return nil
+	}
+	err := argsVal.Interface().(Validator).Validate()
+	if err == nil {
+		return nil
+	}
+	if e, ok := err.(Error); ok {
+		return e
+	}
+	return NewUnprocessableEntityError("validation_failed", err.Error())
+}
@@ -0,0 +1,79 @@
+package restik
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type validateNested struct {
+	Name string `json:"name" validate:"min=3"`
+}
+
+type validateArg struct {
+	Limit  int             `query:"limit" validate:"min=1,max=100"`
+	Page   *int            `query:"page" validate:"min=1"`
+	Email  string          `json:"email" validate:"omitempty,email"`
+	Plan   string          `json:"plan" validate:"oneof=free pro"`
+	Title  string          `json:"title" validate:"required,min=3"`
+	Nested *validateNested `json:"n"`
+	Inner  validateNested  `json:"inner"`
+}
+
+func TestValidateRules(t *testing.T) {
+	r := NewRouter()
+	r.Post("/v", func(a *validateArg) (string, error) { return "ok", nil })
+
+	tests := []struct {
+		name   string
+		query  string
+		body   string
+		status int
+		field  string
+	}{
+		{"optional fields omitted", "", `{"title":"abc"}`, http.StatusOK, ""},
+		{"required missing", "", `{}`, http.StatusUnprocessableEntity, `"field":"title"`},
+		{"required too short", "", `{"title":"ab"}`, http.StatusUnprocessableEntity, `"rule":"min"`},
+		{"limit set below min", "?limit=-1", `{"title":"abc"}`, http.StatusUnprocessableEntity, `"field":"limit"`},
+		{"limit above max", "?limit=101", `{"title":"abc"}`, http.StatusUnprocessableEntity, `"field":"limit"`},
+		{"limit valid", "?limit=10", `{"title":"abc"}`, http.StatusOK, ""},
+		{"pointer set to zero", "?page=0", `{"title":"abc"}`, http.StatusUnprocessableEntity, `"field":"page"`},
+		{"invalid email", "", `{"title":"abc","email":"x"}`, http.StatusUnprocessableEntity, `"field":"email"`},
+		{"invalid oneof", "", `{"title":"abc","plan":"gold"}`, http.StatusUnprocessableEntity, `"field":"plan"`},
+		{"nil nested pointer", "", `{"title":"abc"}`, http.StatusOK, ""},
+		{"nested pointer", "", `{"title":"abc","n":{"name":"a"}}`, http.StatusUnprocessableEntity, `"field":"n.name"`},
+		{"nested value", "", `{"title":"abc","inner":{"name":"a"}}`, http.StatusUnprocessableEntity, `"field":"inner.name"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/v"+tt.query, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.Handler().ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body)
+			}
+			if tt.field != "" && !strings.Contains(w.Body.String(), tt.field) {
+				t.Errorf("body = %s, want %s", w.Body, tt.field)
+			}
+		})
+	}
+}
+
+type validateNode struct {
+	Name string        `json:"name" validate:"required"`
+	Next *validateNode `json:"next"`
+}
+
+func TestValidateRecursiveType(t *testing.T) {
+	// self-referencing types are validated at first level only
+	v, err := newValidator(reflect.TypeOf(validateNode{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if violations := v.validate(reflect.ValueOf(validateNode{Next: &validateNode{}})); len(violations) != 1 || violations[0].Field != "name" {
+		t.Errorf("violations = %v", violations)
+	}
+}
@@ -0,0 +1,466 @@
+package restik
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// WebSocketHandler handle websocket connection. Connection is closed
+// after handler returns
+type WebSocketHandler func(ws *WebSocketConn, r *Request)
+
+// Message types of websocket frames
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+)
+
+// Status codes of websocket close frames
+const (
+	CloseNormalClosure           = 1000
+	CloseGoingAway               = 1001
+	CloseProtocolError           = 1002
+	CloseUnsupportedData         = 1003
+	CloseNoStatusReceived        = 1005
+	CloseInvalidFramePayloadData = 1007
+	ClosePolicyViolation         = 1008
+	CloseMessageTooBig           = 1009
+	CloseInternalServerErr       = 1011
+)
+
+const (
+	continuationFrame = 0
+	maxControlPayload = 125
+	websocketGUID     = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+)
+
+// DefaultMaxMessageSize is default limit of websocket message size
+const DefaultMaxMessageSize = 1 << 20
+
+// CloseError is returned by read methods when peer closes connection
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	if e.Text == "" {
+		return fmt.Sprintf("websocket: close %d", e.Code)
+	}
+	return fmt.Sprintf("websocket: close %d: %s", e.Code, e.Text)
+}
+
+// WebSocketConn is server side of websocket connection. Reads must be
+// made from one goroutine, writes are safe for concurrent use
+type WebSocketConn struct {
+	// MaxMessageSize limit size of read messages, connection is closed
+	// with CloseMessageTooBig status if limit is exceeded. Zero or negative
+	// value is DefaultMaxMessageSize
+	MaxMessageSize int64
+
+	conn  net.Conn
+	br    *bufio.Reader
+	codec Codec
+
+	wmu       sync.Mutex
+	closeSent bool
+}
+
+// WebSocket add websocket endpoint to router and return its route.
+// Route handles GET requests, runs through middlewares like other routes
+// and its handler receives path variables in Request.Vars
+//
+// Using:
+//
+//	r.WebSocket("/chats/{id}", func(ws *restik.WebSocketConn, req *restik.Request) {
+//		for {
+//			var msg chatMessage
+//			if err := ws.ReadJSON(&msg); err != nil {
+//				return
+//			}
+//			...
+//		}
+//	})
+func (r *Router) WebSocket(endpoint string, handler WebSocketHandler) *Route {
+	rt := NewRoute("GET", endpoint, handler)
+	r.Add(rt)
+	return rt
+}
+
+// WebSocket add websocket endpoint to group and return its route
+func (g *Group) WebSocket(endpoint string, handler WebSocketHandler) *Route {
+	return g.newRoute("GET", endpoint, handler)
+}
+
+// CheckOrigin set function which allow websocket handshakes by Origin
+// header. By default only requests without Origin or with Origin matching
+// Host are allowed
+func (rt *Route) CheckOrigin(f func(*Request) bool) *Route {
+	rt.checkOrigin = f
+	return rt
+}
+
+func webSocketHandlerOf(fn interface{}) (WebSocketHandler, bool) {
+	switch h := fn.(type) {
+	case WebSocketHandler:
+		return h, true
+	case func(*WebSocketConn, *Request):
+		return h, true
+	}
+	return nil, false
+}
+
+// serveWebSocket upgrade request to websocket and run route handler
+func (rt *Route) serveWebSocket(rw ResponseWriter, rr *Request) {
+	checkOrigin := rt.checkOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	switch {
+	case !headerHasToken(rr.Header, "Connection", "upgrade") || !headerHasToken(rr.Header, "Upgrade", "websocket"):
+		rw.Header().Set("Upgrade", "websocket")
+		rw.WriteError(NewError(http.StatusUpgradeRequired, "upgrade_required", "Websocket upgrade required"))
+		return
+	case rr.Header.Get("Sec-WebSocket-Version") != "13":
+		rw.Header().Set("Sec-WebSocket-Version", "13")
+		rw.WriteError(NewError(http.StatusUpgradeRequired, "unsupported_version", "Unsupported websocket version"))
+		return
+	case !checkOrigin(rr):
+		rw.WriteError(NewForbiddenError("origin_not_allowed", "Origin not allowed"))
+		return
+	}
+	key := rr.Header.Get("Sec-WebSocket-Key")
+	if b, err := base64.StdEncoding.DecodeString(key); err != nil || len(b) != 16 {
+		rw.WriteError(NewBadRequestError("invalid_websocket_key", "Invalid Sec-WebSocket-Key header"))
+		return
+	}
+	hj, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		rw.WriteError(NewInternalError("websocket_unsupported", "Websocket is not supported"))
+		return
+	}
+	conn, brw, err := hj.Hijack()
+	if err != nil {
+		rw.WriteError(err)
+		return
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	buf.WriteString("Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n")
+	// headers set by middlewares, e.g. cookies
+	rw.Header().Write(&buf)
+	buf.WriteString("\r\n")
+	conn.SetDeadline(time.Time{})
+	if _, err := conn.Write(buf.Bytes()); err != nil {
+		conn.Close()
+		return
+	}
+
+	codec, ok := rt.router.codecs.find("application/json")
+	if !ok {
+		codec = JSONCodec
+	}
+	ws := &WebSocketConn{
+		MaxMessageSize: DefaultMaxMessageSize,
+		conn:           conn,
+		br:             brw.Reader,
+		codec:          codec,
+	}
+	defer ws.Close()
+	rt.webSocketHandler(ws, rr)
+}
+
+// ReadMessage read next data message. Ping frames are answered with pong,
+// close frames are answered and returned as *CloseError
+func (ws *WebSocketConn) ReadMessage() (messageType int, data []byte, err error) {
+	var msg []byte
+	for {
+		fin, opcode, payload, err := ws.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		switch opcode {
+		case PingMessage:
+			if err := ws.WriteMessage(PongMessage, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			continue
+		case CloseMessage:
+			return 0, nil, ws.handleClose(payload)
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				return 0, nil, ws.fail(CloseProtocolError, "unexpected data frame")
+			}
+			messageType = opcode
+		case continuationFrame:
+			if messageType == 0 {
+				return 0, nil, ws.fail(CloseProtocolError, "unexpected continuation frame")
+			}
+		default:
+			return 0, nil, ws.fail(CloseProtocolError, "unknown opcode")
+		}
+		if int64(len(msg)+len(payload)) > ws.maxMessageSize() {
+			return 0, nil, ws.fail(CloseMessageTooBig, "message too big")
+		}
+		msg = append(msg, payload...)
+		if fin {
+			break
+		}
+	}
+	if messageType == TextMessage && !utf8.Valid(msg) {
+		return 0, nil, ws.fail(CloseInvalidFramePayloadData, "invalid utf-8 text")
+	}
+	return messageType, msg, nil
+}
+
+// WriteMessage write message of given type as single frame
+func (ws *WebSocketConn) WriteMessage(messageType int, data []byte) error {
+	switch messageType {
+	case TextMessage, BinaryMessage:
+	case CloseMessage, PingMessage, PongMessage:
+		if len(data) > maxControlPayload {
+			return errors.New("websocket: control frame payload is too large")
+		}
+	default:
+		return fmt.Errorf("websocket: unknown message type %d", messageType)
+	}
+	ws.wmu.Lock()
+	defer ws.wmu.Unlock()
+	if ws.closeSent {
+		return errors.New("websocket: close frame is sent")
+	}
+	if messageType == CloseMessage {
+		ws.closeSent = true
+	}
+	return ws.writeFrame(messageType, data)
+}
+
+// ReadJSON read message and decode it into v by router JSON codec
+func (ws *WebSocketConn) ReadJSON(v interface{}) error {
+	_, data, err := ws.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return ws.codec.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// WriteJSON encode v by router JSON codec and write it as text message
+func (ws *WebSocketConn) WriteJSON(v interface{}) error {
+	var buf bytes.Buffer
+	if err := ws.codec.NewEncoder(&buf).Encode(v); err != nil {
+		return err
+	}
+	return ws.WriteMessage(TextMessage, bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+}
+
+// WriteClose write close frame with status code and text. Codes
+// reserved for endpoints like CloseNoStatusReceived are not allowed
+func (ws *WebSocketConn) WriteClose(code int, text string) error {
+	if !validCloseCode(code) {
+		return fmt.Errorf("websocket: invalid close code %d", code)
+	}
+	return ws.WriteMessage(CloseMessage, closePayload(code, text))
+}
+
+// Close send normal close frame if it is not sent and close connection
+func (ws *WebSocketConn) Close() error {
+	ws.WriteClose(CloseNormalClosure, "")
+	return ws.conn.Close()
+}
+
+// SetReadDeadline set deadline of reads from connection
+func (ws *WebSocketConn) SetReadDeadline(t time.Time) error {
+	return ws.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline set deadline of writes to connection
+func (ws *WebSocketConn) SetWriteDeadline(t time.Time) error {
+	return ws.conn.SetWriteDeadline(t)
+}
+
+// RemoteAddr return address of client
+func (ws *WebSocketConn) RemoteAddr() net.Addr {
+	return ws.conn.RemoteAddr()
+}
+
+// readFrame read one frame from client and unmask its payload
+func (ws *WebSocketConn) readFrame() (fin bool, opcode int, payload []byte, err error) {
+	var head [2]byte
+	if _, err = io.ReadFull(ws.br, head[:]); err != nil {
+		return
+	}
+	fin = head[0]&0x80 != 0
+	opcode = int(head[0] & 0x0f)
+	if head[0]&0x70 != 0 {
+		return false, 0, nil, ws.fail(CloseProtocolError, "reserved bits are set")
+	}
+	if head[1]&0x80 == 0 {
+		return false, 0, nil, ws.fail(CloseProtocolError, "client frame is not masked")
+	}
+	length := uint64(head[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(ws.br, ext[:]); err != nil {
+			return
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(ws.br, ext[:]); err != nil {
+			return
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+		if length&(1<<63) != 0 {
+			return false, 0, nil, ws.fail(CloseProtocolError, "invalid payload length")
+		}
+	}
+	if opcode >= CloseMessage && (!fin || length > maxControlPayload) {
+		return false, 0, nil, ws.fail(CloseProtocolError, "invalid control frame")
+	}
+	if length > uint64(ws.maxMessageSize()) {
+		return false, 0, nil, ws.fail(CloseMessageTooBig, "message too big")
+	}
+	var mask [4]byte
+	if _, err = io.ReadFull(ws.br, mask[:]); err != nil {
+		return
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(ws.br, payload); err != nil {
+		return
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, opcode, payload, nil
+}
+
+// maxMessageSize return limit of read messages
+func (ws *WebSocketConn) maxMessageSize() int64 {
+	if ws.MaxMessageSize <= 0 {
+		return DefaultMaxMessageSize
+	}
+	return ws.MaxMessageSize
+}
+
+// writeFrame write unmasked frame, caller must hold write lock
+func (ws *WebSocketConn) writeFrame(opcode int, payload []byte) error {
+	head := make([]byte, 2, 10)
+	head[0] = 0x80 | byte(opcode)
+	switch n := len(payload); {
+	case n <= 125:
+		head[1] = byte(n)
+	case n <= 0xffff:
+		head[1] = 126
+		head = append(head, byte(n>>8), byte(n))
+	default:
+		head[1] = 127
+		head = head[:10]
+		binary.BigEndian.PutUint64(head[2:], uint64(n))
+	}
+	if _, err := ws.conn.Write(head); err != nil {
+		return err
+	}
+	_, err := ws.conn.Write(payload)
+	return err
+}
+
+// handleClose answer close frame of client and return it as error
+func (ws *WebSocketConn) handleClose(payload []byte) error {
+	closeErr := &CloseError{Code: CloseNoStatusReceived}
+	switch {
+	case len(payload) == 1:
+		return ws.fail(CloseProtocolError, "invalid close frame")
+	case len(payload) >= 2:
+		closeErr.Code = int(binary.BigEndian.Uint16(payload))
+		closeErr.Text = string(payload[2:])
+		if !validCloseCode(closeErr.Code) {
+			return ws.fail(CloseProtocolError, "invalid close code")
+		}
+		if !utf8.ValidString(closeErr.Text) {
+			return ws.fail(CloseInvalidFramePayloadData, "invalid utf-8 close reason")
+		}
+		ws.WriteMessage(CloseMessage, payload[:2])
+	default:
+		ws.WriteMessage(CloseMessage, nil)
+	}
+	return closeErr
+}
+
+// fail close connection with status code because of protocol violation
+func (ws *WebSocketConn) fail(code int, text string) error {
+	ws.WriteClose(code, text)
+	return &CloseError{Code: code, Text: text}
+}
+
+func closePayload(code int, text string) []byte {
+	if len(text) > maxControlPayload-2 {
+		text = text[:maxControlPayload-2]
+	}
+	payload := make([]byte, 2, 2+len(text))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	return append(payload, text...)
+}
+
+// validCloseCode report whether close status code can be sent in close
+// frame. Codes 1005, 1006 and 1015 are reserved for endpoints by RFC 6455
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1014:
+		return true
+	case code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
+
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// headerHasToken report whether comma separated header contains token
+func headerHasToken(header http.Header, name, token string) bool {
+	for _, v := range header.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sameOrigin allow requests without Origin header or with Origin host
+// equal to Host header
+func sameOrigin(r *Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
@@ -0,0 +1,350 @@
+package restik
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// wsClient is minimal websocket client writing masked frames
+type wsClient struct {
+	t    *testing.T
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func dialWebSocket(t *testing.T, srv *httptest.Server, path string, header http.Header) (*wsClient, *http.Response) {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	req, _ := http.NewRequest("GET", srv.URL+path, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if err := req.Write(conn); err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &wsClient{t: t, conn: conn, br: br}, resp
+}
+
+func (c *wsClient) writeFrame(fin bool, opcode int, payload []byte) {
+	c.t.Helper()
+	c.writeRaw(fin, opcode, uint64(len(payload)), payload)
+}
+
+// writeRaw write masked frame header with given length and payload
+func (c *wsClient) writeRaw(fin bool, opcode int, length uint64, payload []byte) {
+	c.t.Helper()
+	head := []byte{byte(opcode), 0x80}
+	if fin {
+		head[0] |= 0x80
+	}
+	switch {
+	case length <= 125:
+		head[1] |= byte(length)
+	case length <= 0xffff:
+		head[1] |= 126
+		head = append(head, byte(length>>8), byte(length))
+	default:
+		head[1] |= 127
+		head = append(head, make([]byte, 8)...)
+		binary.BigEndian.PutUint64(head[2:], length)
+	}
+	mask := []byte{0x12, 0x34, 0x56, 0x78}
+	head = append(head, mask...)
+	masked := make([]byte, len(payload))
+	for i := range payload {
+		masked[i] = payload[i] ^ mask[i%4]
+	}
+	if _, err := c.conn.Write(append(head, masked...)); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *wsClient) readFrame() (opcode int, payload []byte) {
+	c.t.Helper()
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		c.t.Fatal(err)
+	}
+	if head[1]&0x80 != 0 {
+		c.t.Fatal("server frame is masked")
+	}
+	length := uint64(head[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		io.ReadFull(c.br, ext[:])
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		io.ReadFull(c.br, ext[:])
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		c.t.Fatal(err)
+	}
+	return int(head[0] & 0x0f), payload
+}
+
+// expectClose read close frame and check its status code
+func (c *wsClient) expectClose(code int) {
+	c.t.Helper()
+	opcode, payload := c.readFrame()
+	if opcode != CloseMessage {
+		c.t.Fatalf("opcode = %d, want close", opcode)
+	}
+	if len(payload) < 2 || int(binary.BigEndian.Uint16(payload)) != code {
+		c.t.Fatalf("close payload = %q, want code %d", payload, code)
+	}
+}
+
+func newWebSocketServer(t *testing.T, setup func(ws *WebSocketConn)) (*httptest.Server, chan error) {
+	errs := make(chan error, 1)
+	r := NewRouter()
+	r.WebSocket("/ws/{room}", func(ws *WebSocketConn, req *Request) {
+		if setup != nil {
+			setup(ws)
+		}
+		for {
+			mt, data, err := ws.ReadMessage()
+			if err != nil {
+				errs <- err
+				return
+			}
+			if mt == TextMessage {
+				data = append([]byte(req.Vars.String("room")+":"), data...)
+			}
+			if err := ws.WriteMessage(mt, data); err != nil {
+				errs <- err
+				return
+			}
+		}
+	})
+	srv := httptest.NewServer(r.Handler())
+	t.Cleanup(srv.Close)
+	return srv, errs
+}
+
+func closeCode(t *testing.T, errs chan error) int {
+	t.Helper()
+	select {
+	case err := <-errs:
+		var ce *CloseError
+		if !errors.As(err, &ce) {
+			t.Fatalf("handler error = %v, want *CloseError", err)
+		}
+		return ce.Code
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler did not return")
+	}
+	return 0
+}
+
+func TestWebSocketHandshake(t *testing.T) {
+	srv, _ := newWebSocketServer(t, nil)
+	_, resp := dialWebSocket(t, srv, "/ws/a", nil)
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status = %d, want 101", resp.StatusCode)
+	}
+	// example key of RFC 6455 section 1.3
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Sec-WebSocket-Accept = %q", got)
+	}
+
+	tests := []struct {
+		name   string
+		header http.Header
+		status int
+	}{
+		{"version", http.Header{"Sec-Websocket-Version": {"8"}}, http.StatusUpgradeRequired},
+		{"key", http.Header{"Sec-Websocket-Key": {"short"}}, http.StatusBadRequest},
+		{"origin", http.Header{"Origin": {"http://evil.example"}}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, resp := dialWebSocket(t, srv, "/ws/a", tt.header)
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+
+	resp, err := http.Get(srv.URL + "/ws/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUpgradeRequired {
+		t.Errorf("plain GET status = %d, want 426", resp.StatusCode)
+	}
+}
+
+func TestWebSocketEcho(t *testing.T) {
+	srv, _ := newWebSocketServer(t, nil)
+	c, _ := dialWebSocket(t, srv, "/ws/room1", nil)
+
+	c.writeFrame(true, TextMessage, []byte("hello"))
+	if op, p := c.readFrame(); op != TextMessage || string(p) != "room1:hello" {
+		t.Fatalf("got %d %q", op, p)
+	}
+
+	// extended 16-bit length with masking over several words
+	long := []byte(strings.Repeat("x", 300))
+	c.writeFrame(true, BinaryMessage, long)
+	if op, p := c.readFrame(); op != BinaryMessage || string(p) != string(long) {
+		t.Fatalf("got %d len %d", op, len(p))
+	}
+}
+
+func TestWebSocketFragmentation(t *testing.T) {
+	srv, errs := newWebSocketServer(t, nil)
+	c, _ := dialWebSocket(t, srv, "/ws/f", nil)
+
+	c.writeFrame(false, TextMessage, []byte("he"))
+	// control frames can be interleaved with fragments
+	c.writeFrame(true, PingMessage, []byte("p"))
+	c.writeFrame(false, continuationFrame, []byte("ll"))
+	c.writeFrame(true, continuationFrame, []byte("o"))
+	if op, p := c.readFrame(); op != PongMessage || string(p) != "p" {
+		t.Fatalf("got %d %q, want pong", op, p)
+	}
+	if op, p := c.readFrame(); op != TextMessage || string(p) != "f:hello" {
+		t.Fatalf("got %d %q", op, p)
+	}
+
+	c.writeFrame(true, continuationFrame, []byte("x"))
+	c.expectClose(CloseProtocolError)
+	if code := closeCode(t, errs); code != CloseProtocolError {
+		t.Errorf("code = %d", code)
+	}
+}
+
+func TestWebSocketPingPong(t *testing.T) {
+	srv, _ := newWebSocketServer(t, nil)
+	c, _ := dialWebSocket(t, srv, "/ws/p", nil)
+
+	c.writeFrame(true, PingMessage, []byte("ping"))
+	if op, p := c.readFrame(); op != PongMessage || string(p) != "ping" {
+		t.Fatalf("got %d %q, want pong", op, p)
+	}
+	// unsolicited pong is ignored
+	c.writeFrame(true, PongMessage, nil)
+	c.writeFrame(true, TextMessage, []byte("x"))
+	if op, p := c.readFrame(); op != TextMessage || string(p) != "p:x" {
+		t.Fatalf("got %d %q", op, p)
+	}
+}
+
+func TestWebSocketClose(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte
+		reply   int
+		err     int
+	}{
+		{"normal", closePayload(CloseGoingAway, "bye"), CloseGoingAway, CloseGoingAway},
+		{"no status", nil, 0, CloseNoStatusReceived},
+		{"one byte", []byte{3}, CloseProtocolError, CloseProtocolError},
+		{"reserved 1005", closePayload(CloseNoStatusReceived, ""), CloseProtocolError, CloseProtocolError},
+		{"reserved 1006", closePayload(1006, ""), CloseProtocolError, CloseProtocolError},
+		{"reserved 1015", closePayload(1015, ""), CloseProtocolError, CloseProtocolError},
+		{"invalid utf-8", append(closePayload(CloseNormalClosure, ""), 0xff), CloseInvalidFramePayloadData, CloseInvalidFramePayloadData},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, errs := newWebSocketServer(t, nil)
+			c, _ := dialWebSocket(t, srv, "/ws/c", nil)
+			c.writeFrame(true, CloseMessage, tt.payload)
+			if tt.reply == 0 {
+				if op, p := c.readFrame(); op != CloseMessage || len(p) != 0 {
+					t.Fatalf("got %d %q, want empty close", op, p)
+				}
+			} else {
+				c.expectClose(tt.reply)
+			}
+			if code := closeCode(t, errs); code != tt.err {
+				t.Errorf("code = %d, want %d", code, tt.err)
+			}
+		})
+	}
+}
+
+func TestWebSocketWriteCloseReserved(t *testing.T) {
+	ws := &WebSocketConn{}
+	for _, code := range []int{CloseNoStatusReceived, 1006, 1015, 999, 5000} {
+		if err := ws.WriteClose(code, ""); err == nil {
+			t.Errorf("WriteClose(%d) succeeded", code)
+		}
+	}
+}
+
+func TestWebSocketOversize(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int64
+		send  func(c *wsClient)
+		code  int
+	}{
+		{"frame over limit", 10, func(c *wsClient) {
+			c.writeFrame(true, BinaryMessage, make([]byte, 11))
+		}, CloseMessageTooBig},
+		{"fragments over limit", 10, func(c *wsClient) {
+			c.writeFrame(false, BinaryMessage, make([]byte, 6))
+			c.writeFrame(true, continuationFrame, make([]byte, 6))
+		}, CloseMessageTooBig},
+		{"zero limit is default", 0, func(c *wsClient) {
+			c.writeRaw(true, BinaryMessage, 1<<40, nil)
+		}, CloseMessageTooBig},
+		{"length top bit", 0, func(c *wsClient) {
+			c.writeRaw(true, BinaryMessage, 1<<63, nil)
+		}, CloseProtocolError},
+		{"long control frame", 0, func(c *wsClient) {
+			c.writeFrame(true, PingMessage, make([]byte, 126))
+		}, CloseProtocolError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, errs := newWebSocketServer(t, func(ws *WebSocketConn) {
+				ws.MaxMessageSize = tt.limit
+			})
+			c, _ := dialWebSocket(t, srv, "/ws/o", nil)
+			tt.send(c)
+			c.expectClose(tt.code)
+			if code := closeCode(t, errs); code != tt.code {
+				t.Errorf("code = %d, want %d", code, tt.code)
+			}
+		})
+	}
+}
+
+func TestWebSocketUnmaskedFrame(t *testing.T) {
+	srv, errs := newWebSocketServer(t, nil)
+	c, _ := dialWebSocket(t, srv, "/ws/u", nil)
+	c.conn.Write([]byte{0x81, 0x01, 'x'})
+	c.expectClose(CloseProtocolError)
+	if code := closeCode(t, errs); code != CloseProtocolError {
+		t.Errorf("code = %d", code)
+	}
+}